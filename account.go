@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Account is one of several trading accounts sharing the config. Zero risk
+// settings fall back to the top level ones.
+type Account struct {
+	Name   string `json:"name"`
+	Broker string `json:"broker,omitempty"`
+
+	// Logins and keys for the broker, usually secret references
+	Credentials map[string]string `json:"credentials,omitempty"`
+
+	AccountBalance float64 `json:"accountBalance"`
+	LossTolerance  float64 `json:"lossTolerance,omitempty"`
+	ProfitPercent  float64 `json:"profitPercent,omitempty"`
+}
+
+func (a Account) Validate() error {
+	// The name ends up in file names
+	if a.Name == "" || strings.ContainsAny(a.Name, `/\.: `) {
+		return fmt.Errorf("account name %q must be non-empty and usable in a file name", a.Name)
+	}
+
+	if a.AccountBalance <= 0 {
+		return fmt.Errorf("account %s: accountBalance must be positive, got %v", a.Name, a.AccountBalance)
+	}
+
+	if a.LossTolerance < 0 || a.LossTolerance >= 1 {
+		return fmt.Errorf("account %s: lossTolerance must be between 0 and 1, got %v", a.Name, a.LossTolerance)
+	}
+
+	if a.ProfitPercent < 0 {
+		return fmt.Errorf("account %s: profitPercent must be positive, got %v", a.Name, a.ProfitPercent)
+	}
+
+	return nil
+}
+
+func validateAccounts(accounts []Account) error {
+	seen := map[string]bool{}
+	for _, a := range accounts {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		if seen[a.Name] {
+			return fmt.Errorf("account %s is defined twice", a.Name)
+		}
+		seen[a.Name] = true
+	}
+	return nil
+}
+
+// forAccount returns the config a run for the named account uses: its
+// balance and risk settings, and its own journal in the runs directory
+func (c Config) forAccount(name string) (Config, error) {
+	for _, a := range c.Accounts {
+		if a.Name != name {
+			continue
+		}
+
+		c.AccountBalance = a.AccountBalance
+		if a.LossTolerance > 0 {
+			c.LossTolerance = a.LossTolerance
+		}
+		if a.ProfitPercent > 0 {
+			c.ProfitPercent = a.ProfitPercent
+		}
+		c.RunsDir = filepath.Join(c.RunsDir, a.Name)
+		c.account = a.Name
+
+		return c, nil
+	}
+
+	if len(c.Accounts) == 0 {
+		return c, fmt.Errorf("unknown account %s, no accounts are configured", name)
+	}
+	return c, fmt.Errorf("unknown account %s", name)
+}
+
+// ForAccount returns an engine sizing for the named account. It shares the
+// HTTP client and identifier cache with e.
+func (e *Engine) ForAccount(name string) (*Engine, error) {
+	cfg, err := e.cfg.forAccount(name)
+	if err != nil {
+		return nil, err
+	}
+
+	a := *e
+	a.cfg = cfg
+	return &a, nil
+}
+
+// accountPath gives every account its own output: opg.json -> opg.ira.json
+func accountPath(outputPath, account string) string {
+	ext := filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + "." + account + ext
+}
+
+// splitAccounts runs the plan once for every account. Each account sizes the
+// positions from its own balance and loss tolerance, so the plan is split
+// across them in proportion to the risk they can take.
+func splitAccounts(engine *Engine, run func(engine *Engine, outputPath string) (*Summary, error), outputPath string) (*Summary, error) {
+	accounts := engine.Config().Accounts
+	if len(accounts) == 0 {
+		return nil, errors.New("-split needs accounts in the config")
+	}
+
+	var last *Summary
+	var errs []error
+	for _, a := range accounts {
+		e, err := engine.ForAccount(a.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		summary, err := run(e, accountPath(outputPath, a.Name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", a.Name, err))
+		}
+		if summary != nil {
+			last = summary
+		}
+	}
+
+	return last, errors.Join(errs...)
+}
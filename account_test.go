@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestForAccount(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Accounts = []Account{
+		{Name: "main", Broker: "ibkr", AccountBalance: 50000},
+		{Name: "ira", AccountBalance: 20000, LossTolerance: .01},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	main, err := cfg.forAccount("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if main.AccountBalance != 50000 || main.LossTolerance != .02 || main.RunsDir != filepath.Join("runs", "main") {
+		t.Errorf("main: got balance %v tolerance %v runs %s", main.AccountBalance, main.LossTolerance, main.RunsDir)
+	}
+
+	ira, _ := cfg.forAccount("ira")
+	if ira.LossTolerance != .01 || ira.account != "ira" {
+		t.Errorf("ira: got tolerance %v account %q", ira.LossTolerance, ira.account)
+	}
+
+	if _, err := cfg.forAccount("nope"); err == nil {
+		t.Error("want error for an unknown account")
+	}
+}
+
+func TestValidateAccounts(t *testing.T) {
+	for _, accounts := range [][]Account{
+		{{Name: "a", AccountBalance: 1}, {Name: "a", AccountBalance: 2}},
+		{{Name: "../a", AccountBalance: 1}},
+		{{Name: "a"}},
+		{{Name: "a", AccountBalance: 1, LossTolerance: 2}},
+	} {
+		if err := validateAccounts(accounts); err == nil {
+			t.Errorf("%+v: want error", accounts)
+		}
+	}
+}
+
+func TestSplitAccountsSizesEach(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Accounts = []Account{
+		{Name: "main", AccountBalance: 30000},
+		{Name: "ira", AccountBalance: 10000},
+	}
+
+	shares := map[string]int{}
+	_, err := splitAccounts(NewEngine(cfg, nil), func(e *Engine, output string) (*Summary, error) {
+		shares[output] = e.Calculate("MSFT", -.1, 108.86).Shares
+		return &Summary{}, nil
+	}, "out/opg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three times the balance, three times the shares
+	main, ira := shares[filepath.Join("out", "opg.main.json")], shares[filepath.Join("out", "opg.ira.json")]
+	if ira == 0 || main < 3*ira-1 || main > 3*ira+1 {
+		t.Errorf("got %v, want main three times ira", shares)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+type AlertConfig struct {
+	// How often quotes are polled, "15s" by default
+	PollInterval Duration `json:"pollInterval,omitempty"`
+
+	Notify NotifyConfig `json:"notify"`
+}
+
+// A planned entry that hasn't been triggered yet
+type entryWatch struct {
+	Selection
+	last  float64
+	seen  bool
+	fired bool
+}
+
+// entryAlerter notifies once per ticker when the price trades through the
+// planned entry: upwards for longs, downwards for shorts
+type entryAlerter struct {
+	watches  []*entryWatch
+	quotes   func(ctx context.Context, tickers []string) (map[string]float64, error)
+	notifier Notifier
+
+	// Prices and amounts in the messages read like in the report
+	engine *Engine
+	locale *Locale
+
+	// Whether the ticker's market is trading at t, always when nil
+	open func(ticker string, t time.Time) bool
+}
+
+func newEntryAlerter(e *Engine, l *Locale, selections []Selection, quotes func(context.Context, []string) (map[string]float64, error), notifier Notifier) *entryAlerter {
+	a := &entryAlerter{quotes: quotes, notifier: notifier, engine: e, locale: l}
+
+	for _, sel := range selections {
+		// Nothing to enter for zero-size positions
+		if sel.Shares <= 0 || sel.EntryPrice <= 0 {
+			continue
+		}
+		a.watches = append(a.watches, &entryWatch{Selection: sel})
+	}
+
+	return a
+}
+
+func (a *entryAlerter) pending() []string {
+	var tickers []string
+	for _, w := range a.watches {
+		if !w.fired {
+			tickers = append(tickers, w.Ticker)
+		}
+	}
+	return tickers
+}
+
+// poll fetches the quotes once and fires the alerts for every entry crossed
+// since the previous poll. It returns how many entries are still pending.
+func (a *entryAlerter) poll(ctx context.Context) (int, error) {
+	pending := a.pending()
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	// Quotes from a closed venue are stale, they can't trigger anything
+	var tickers []string
+	for _, t := range pending {
+		if a.open == nil || a.open(t, time.Now()) {
+			tickers = append(tickers, t)
+		}
+	}
+	if len(tickers) == 0 {
+		return len(pending), nil
+	}
+
+	quotes, err := a.quotes(ctx, tickers)
+	if err != nil {
+		return len(pending), err
+	}
+
+	for _, w := range a.watches {
+		price, ok := quotes[w.Ticker]
+		if w.fired || !ok {
+			continue
+		}
+
+		// The first quote only sets where the price is coming from
+		if w.seen && crossed(w.Long(), w.last, price, w.EntryPrice) {
+			w.fired = true
+
+			side := "SHORT"
+			if w.Long() {
+				side = "LONG"
+			}
+
+			title := fmt.Sprintf("%s %s entry triggered", w.Ticker, side)
+			msg := a.message(w.Selection, price)
+
+			if err := a.notifier.Notify(ctx, title, msg); err != nil {
+				log.Printf("Error sending alert for %s, %v", w.Ticker, err)
+			}
+		}
+
+		w.last, w.seen = price, true
+	}
+
+	return len(a.pending()), nil
+}
+
+// message describes the triggered entry, in the locale and with the
+// decimals of the ticker's tick size
+func (a *entryAlerter) message(sel Selection, price float64) string {
+	e, l := a.engine, a.locale
+
+	return fmt.Sprintf("Traded at %s through entry %s. %d shares, stop %s, target %s, profit %s",
+		e.formatPrice(l, sel.Ticker, price),
+		e.formatPrice(l, sel.Ticker, sel.EntryPrice), sel.Shares,
+		e.formatPrice(l, sel.Ticker, sel.StopLossPrice),
+		e.formatPrice(l, sel.Ticker, sel.TakeProfitPrice),
+		e.formatMoney(l, sel.Profit))
+}
+
+// tickerSession is when the ticker trades on t's day: its venue's hours for
+// foreign listings, the US calendar otherwise
+func tickerSession(cfg Config, us Session, ticker string, t time.Time) (time.Time, time.Time, bool) {
+	if suffix, v, ok := cfg.venue(ticker); ok && suffix != "" {
+		return v.Session(t)
+	}
+	return us.Opens, us.Closes, us.Open
+}
+
+// crossed reports whether the move from prev to price went through entry in the trade's direction
+func crossed(long bool, prev, price, entry float64) bool {
+	if long {
+		return prev < entry && price >= entry
+	}
+	return prev > entry && price <= entry
+}
+
+// Watches live quotes for the planned entries and notifies when one triggers after the open
+func alertCommand(ctx context.Context, args []string) error {
+	var opts options
+
+	fs := flag.NewFlagSet("alert", flag.ExitOnError)
+	opts.register(fs)
+	planPath := fs.String("plan", "./opg.json", "selections to watch, as written by run")
+	fs.Parse(args)
+
+	engine, done, err := opts.setup()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	cfg := engine.Config()
+
+	selections, err := ReadSelections(*planPath)
+	if err != nil {
+		return err
+	}
+
+	calendar, err := NewCalendar(cfg.Holidays)
+	if err != nil {
+		return err
+	}
+
+	session := calendar.Session(time.Now())
+
+	l, err := opts.locale(engine)
+	if err != nil {
+		return err
+	}
+
+	alerter := newEntryAlerter(engine, l, selections, engine.FetchQuotes, newNotifier(cfg.Alerts.Notify))
+	if len(alerter.watches) == 0 {
+		return fmt.Errorf("no entries to watch in %s", *planPath)
+	}
+	alerter.open = func(ticker string, t time.Time) bool {
+		opens, closes, open := tickerSession(cfg, session, ticker, t)
+		return open && !t.Before(opens) && t.Before(closes)
+	}
+
+	// Watch from the first open to the last close of the venues in the plan
+	var opens, closes time.Time
+	for _, w := range alerter.watches {
+		o, c, open := tickerSession(cfg, session, w.Ticker, time.Now())
+		if !open {
+			continue
+		}
+		if opens.IsZero() || o.Before(opens) {
+			opens = o
+		}
+		if c.After(closes) {
+			closes = c
+		}
+	}
+
+	if closes.IsZero() {
+		return fmt.Errorf("market closed today (%s)", session.Reason)
+	}
+	if time.Now().After(closes) {
+		return errors.New("market already closed for the day")
+	}
+	session.Opens, session.Closes = opens, closes
+
+	// Entries only trigger once trading has started
+	if wait := time.Until(session.Opens); wait > 0 {
+		log.Printf("Waiting for the open at %s", session.Opens.Format("15:04 MST"))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, session.Closes)
+	defer cancel()
+
+	interval := time.Duration(cfg.Alerts.PollInterval)
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	log.Printf("Watching %d entries every %s until %s", len(alerter.watches), interval, session.Closes.Format("15:04 MST"))
+
+	var remaining int
+	err = every(ctx, interval, func(ctx context.Context) {
+		n, err := alerter.poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error fetching quotes, %v", err)
+		}
+
+		remaining = n
+		if n == 0 {
+			cancel()
+		}
+	})
+
+	if remaining == 0 {
+		log.Println("All entries triggered")
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	titles   []string
+	messages []string
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, title, msg string) error {
+	r.titles = append(r.titles, title)
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+// alertLocale is the English catalog the alerts are tested with
+func alertLocale(t *testing.T) *Locale {
+	t.Helper()
+	l, err := LoadLocale("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestEntryAlerter(t *testing.T) {
+	selections := []Selection{
+		// Gapped down, long with the target above
+		{Ticker: "AMZN", Position: Position{EntryPrice: 160.79, Shares: 9, TakeProfitPrice: 181.73, StopLossPrice: 139.85}},
+		// Gapped up, short with the target below
+		{Ticker: "MSFT", Position: Position{EntryPrice: 108.86, Shares: 13, TakeProfitPrice: 94.35, StopLossPrice: 123.37}},
+		// Nothing to enter
+		{Ticker: "BRK.A", Position: Position{EntryPrice: 576721.27, Shares: 0}},
+	}
+
+	ticks := []map[string]float64{
+		{"AMZN": 159.00, "MSFT": 109.50},
+		{"AMZN": 160.00, "MSFT": 108.86},
+		{"AMZN": 161.00, "MSFT": 108.00},
+		{"AMZN": 158.00},
+		{"AMZN": 162.00},
+	}
+
+	var asked [][]string
+	tick := 0
+	quotes := func(_ context.Context, tickers []string) (map[string]float64, error) {
+		asked = append(asked, tickers)
+		q := ticks[tick]
+		tick++
+		return q, nil
+	}
+
+	notifier := &recordingNotifier{}
+	alerter := newEntryAlerter(NewEngine(DefaultConfig(), nil), alertLocale(t), selections, quotes, notifier)
+
+	if len(alerter.watches) != 2 {
+		t.Fatalf("got %d watches, want 2", len(alerter.watches))
+	}
+
+	wantPending := []int{2, 1, 0}
+	for i, want := range wantPending {
+		n, err := alerter.poll(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("poll %d: %d pending, want %d", i, n, want)
+		}
+	}
+
+	want := []string{"MSFT SHORT entry triggered", "AMZN LONG entry triggered"}
+	if len(notifier.titles) != len(want) || notifier.titles[0] != want[0] || notifier.titles[1] != want[1] {
+		t.Fatalf("got alerts %v, want %v", notifier.titles, want)
+	}
+
+	// Triggered entries aren't polled or alerted again
+	if n, _ := alerter.poll(context.Background()); n != 0 || len(asked) != 3 {
+		t.Fatalf("polled again after every entry triggered")
+	}
+}
+
+func TestEntryAlerterClosedVenue(t *testing.T) {
+	selections := []Selection{
+		{Ticker: "AMZN", Position: Position{EntryPrice: 160.79, Shares: 9}},
+		{Ticker: "VOD.L", Position: Position{EntryPrice: 72.5, Shares: 100}},
+	}
+
+	var asked []string
+	quotes := func(_ context.Context, tickers []string) (map[string]float64, error) {
+		asked = tickers
+		return map[string]float64{}, nil
+	}
+
+	alerter := newEntryAlerter(NewEngine(DefaultConfig(), nil), alertLocale(t), selections, quotes, &recordingNotifier{})
+	alerter.open = func(ticker string, _ time.Time) bool { return ticker != "VOD.L" }
+
+	n, err := alerter.poll(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("got %d pending, %v, want 2", n, err)
+	}
+	if len(asked) != 1 || asked[0] != "AMZN" {
+		t.Errorf("asked quotes for %v, want only AMZN while London is closed", asked)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	n := &slackNotifier{client: srv.Client(), webhook: srv.URL}
+	if err := n.Notify(context.Background(), "AMZN LONG entry triggered", "details"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["text"] != "*AMZN LONG entry triggered*\ndetails" {
+		t.Fatalf("got payload %v", got)
+	}
+}
+
+func TestEntryAlertMessage(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Instruments = map[string]Instrument{"EURUSD": {Type: instrumentForex}}
+	e := NewEngine(cfg, nil)
+
+	de, err := LoadLocale("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amzn := Selection{Ticker: "AMZN", Position: Position{EntryPrice: 1160.79, Shares: 9, StopLossPrice: 1139.85, TakeProfitPrice: 1181.73, Profit: 188.46}}
+
+	tests := []struct {
+		l     *Locale
+		sel   Selection
+		price float64
+		want  string
+	}{
+		{alertLocale(t), amzn, 1161,
+			"Traded at $1,161.00 through entry $1,160.79. 9 shares, stop $1,139.85, target $1,181.73, profit $188.46"},
+		{de, amzn, 1161,
+			"Traded at 1.161,00 $ through entry 1.160,79 $. 9 shares, stop 1.139,85 $, target 1.181,73 $, profit 188,46 $"},
+		// Forex is quoted with the decimals of its pips
+		{alertLocale(t), Selection{Ticker: "EURUSD", Position: Position{EntryPrice: 1.0841, Shares: 2, StopLossPrice: 1.0812, TakeProfitPrice: 1.0875, Profit: 676}}, 1.08432,
+			"Traded at $1.0843 through entry $1.0841. 2 shares, stop $1.0812, target $1.0875, profit $676.00"},
+	}
+
+	for _, tt := range tests {
+		a := newEntryAlerter(e, tt.l, nil, nil, nil)
+		if got := a.message(tt.sel, tt.price); got != tt.want {
+			t.Errorf("%s in %s:\ngot  %s\nwant %s", tt.sel.Ticker, tt.l.Language, got, tt.want)
+		}
+	}
+}
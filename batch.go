@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The outcome of one file in a batch
+type BatchFile struct {
+	Input    string    `json:"input"`
+	Output   string    `json:"output"`
+	RunID    string    `json:"runId,omitempty"`
+	Selected int       `json:"selected"`
+	Failures []Failure `json:"failures,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Consolidated summary of a whole batch
+type BatchSummary struct {
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt time.Time   `json:"finishedAt"`
+	Files      []BatchFile `json:"files"`
+	Succeeded  int         `json:"succeeded"`
+	Failed     int         `json:"failed"`
+	Selected   int         `json:"selected"`
+	Failures   int         `json:"failures"`
+}
+
+// runBatch processes every input with at most workers files in flight, writing
+// each plan to outDir under the input's name
+func runBatch(ctx context.Context, inputs []string, outDir string, workers int, run func(ctx context.Context, input, output string) (*Summary, error)) *BatchSummary {
+	summary := &BatchSummary{StartedAt: time.Now(), Files: make([]BatchFile, len(inputs))}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				input := inputs[i]
+				output := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))+".json")
+
+				file := BatchFile{Input: input, Output: output}
+				s, err := run(ctx, input, output)
+				if s != nil {
+					file.RunID = s.ID
+					file.Selected = s.Selected
+					file.Failures = s.Failures
+				}
+				if err != nil {
+					file.Error = err.Error()
+					log.Printf("Error processing %s, %v", input, err)
+				}
+
+				// Each worker owns its index, no locking needed
+				summary.Files[i] = file
+			}
+		}()
+	}
+
+	for i := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, f := range summary.Files {
+		if f.Input == "" {
+			// Never started because the batch was interrupted
+			summary.Files[i] = BatchFile{Input: inputs[i], Error: "not processed, batch interrupted"}
+			f = summary.Files[i]
+		}
+
+		if f.Error != "" {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+		summary.Selected += f.Selected
+		summary.Failures += len(f.Failures)
+	}
+
+	summary.FinishedAt = time.Now()
+	return summary
+}
+
+// Reprocesses a directory of dated scan files, one output per input
+func batchCommand(ctx context.Context, args []string) error {
+	var opts options
+
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	opts.register(fs)
+	dir := fs.String("dir", "", "directory with the scan files (*.csv)")
+	outDir := fs.String("out", "", "directory for the outputs, one JSON file per input")
+	workers := fs.Int("workers", 4, "files processed at the same time")
+	fs.Parse(args)
+
+	if *dir == "" || *outDir == "" {
+		return errors.New("-dir and -out are required")
+	}
+
+	inputs, err := filepath.Glob(filepath.Join(*dir, "*.csv"))
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("no *.csv files in %s", *dir)
+	}
+
+	// Dated names sort chronologically
+	sort.Strings(inputs)
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+
+	engine, done, err := opts.setup()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	log.Printf("Processing %d files from %s with %d workers", len(inputs), *dir, *workers)
+
+	summary := runBatch(ctx, inputs, *outDir, *workers, func(ctx context.Context, input, output string) (*Summary, error) {
+		return runOnce(ctx, engine, &opts, input, output)
+	})
+
+	log.Printf("Batch finished in %s: %d files ok, %d failed, %d selections, %d ticker failures",
+		summary.FinishedAt.Sub(summary.StartedAt).Round(time.Millisecond),
+		summary.Succeeded, summary.Failed, summary.Selected, summary.Failures)
+
+	for _, f := range summary.Files {
+		if f.Error != "" {
+			log.Printf("  failed %s: %s", f.Input, f.Error)
+		}
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	summaryPath := filepath.Join(*outDir, "batch-summary.json")
+	if err := os.WriteFile(summaryPath, data, 0o644); err != nil {
+		return fmt.Errorf("error writing batch summary: %w", err)
+	}
+	log.Printf("Wrote %s", summaryPath)
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d files failed", summary.Failed, len(inputs))
+	}
+
+	return nil
+}
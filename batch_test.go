@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	inputs := []string{"scans/2024-03-11.csv", "scans/2024-03-12.csv", "scans/2024-03-13.csv", "scans/2024-03-14.csv"}
+
+	var running, peak atomic.Int32
+	run := func(ctx context.Context, input, output string) (*Summary, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if input == "scans/2024-03-13.csv" {
+			return nil, errors.New("record on line 3: wrong number of fields")
+		}
+		return &Summary{ID: filepath.Base(output), Selected: 5, Failures: []Failure{{Ticker: "AMZN", Error: "429"}}}, nil
+	}
+
+	summary := runBatch(context.Background(), inputs, "out", 2, run)
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d files processed at once, want at most 2", p)
+	}
+
+	if summary.Succeeded != 3 || summary.Failed != 1 || summary.Selected != 15 || summary.Failures != 3 {
+		t.Errorf("got %+v", summary)
+	}
+
+	// Files keep the input order whatever order they finished in
+	for i, f := range summary.Files {
+		if f.Input != inputs[i] {
+			t.Errorf("file %d: got %s, want %s", i, f.Input, inputs[i])
+		}
+	}
+
+	if f := summary.Files[0]; f.Output != filepath.Join("out", "2024-03-11.json") || f.RunID != "2024-03-11.json" {
+		t.Errorf("first file: got %+v", f)
+	}
+	if f := summary.Files[2]; f.Error == "" {
+		t.Errorf("third file: expected the error to be recorded")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A cached response as it is stored on disk
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	StatusCode   int         `json:"statusCode"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	StoredAt     time.Time   `json:"storedAt"`
+}
+
+// cachingTransport sends conditional requests (If-None-Match / If-Modified-Since)
+// for GET requests it has seen before, and answers a 304 from the disk cache so
+// the provider doesn't count the full response against our quota.
+type cachingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func newCachingTransport(dir string, next http.RoundTripper) (*cachingTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &cachingTransport{dir: dir, next: next}, nil
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	entry, _ := t.load(path)
+
+	if entry != nil {
+		// Don't modify the caller's request
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		return entry.response(req), nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+
+	// Only responses we can revalidate later are worth keeping
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry = &cacheEntry{
+		URL:          req.URL.String(),
+		ETag:         etag,
+		LastModified: lastModified,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body:         body,
+		StoredAt:     time.Now(),
+	}
+
+	if err := t.store(path, entry); err != nil {
+		// A broken cache must never break a run
+		log.Printf("Error writing cache entry for %s, %v", entry.URL, err)
+	}
+
+	return resp, nil
+}
+
+// Cache files are keyed by the method and full URL
+func (t *cachingTransport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *cachingTransport) load(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (t *cachingTransport) store(path string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file of our own first, so a crash or another worker
+	// storing the same URL can't leave half an entry behind
+	tmp, err := os.CreateTemp(t.dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Default location of the HTTP cache, falls back to a local directory
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".cache"
+	}
+
+	return filepath.Join(dir, "stocktradingcli", "http")
+}
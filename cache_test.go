@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestCacheClient(t *testing.T) (*http.Client, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	transport, err := newCachingTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &http.Client{Transport: transport}, dir
+}
+
+func get(t *testing.T, client *http.Client, method, url string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func cachedEntries(t *testing.T, dir string) int {
+	t.Helper()
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".json") {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestCacheServesNotModifiedFromDisk(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "fresh body")
+	}))
+	defer srv.Close()
+
+	client, dir := newTestCacheClient(t)
+
+	status, body := get(t, client, http.MethodGet, srv.URL+"/news?id=AAPL")
+	if status != http.StatusOK || body != "fresh body" {
+		t.Fatalf("first request: got %d %q", status, body)
+	}
+
+	if n := cachedEntries(t, dir); n != 1 {
+		t.Fatalf("got %d cache entries, want 1", n)
+	}
+
+	status, body = get(t, client, http.MethodGet, srv.URL+"/news?id=AAPL")
+	if status != http.StatusOK || body != "fresh body" {
+		t.Fatalf("revalidated request: got %d %q, want the cached 200", status, body)
+	}
+
+	if hits != 2 {
+		t.Fatalf("server saw %d requests, want 2", hits)
+	}
+}
+
+func TestCacheSkipsResponsesWithoutValidators(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			t.Errorf("unexpected conditional request")
+		}
+		io.WriteString(w, "no validators")
+	}))
+	defer srv.Close()
+
+	client, dir := newTestCacheClient(t)
+
+	for range 2 {
+		if status, body := get(t, client, http.MethodGet, srv.URL); status != http.StatusOK || body != "no validators" {
+			t.Fatalf("got %d %q", status, body)
+		}
+	}
+
+	if n := cachedEntries(t, dir); n != 0 {
+		t.Fatalf("got %d cache entries, want 0", n)
+	}
+}
+
+func TestCachePassesNonGetThrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("unexpected conditional %s request", r.Method)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, r.Method)
+	}))
+	defer srv.Close()
+
+	client, dir := newTestCacheClient(t)
+
+	for range 2 {
+		if status, body := get(t, client, http.MethodPost, srv.URL); status != http.StatusOK || body != http.MethodPost {
+			t.Fatalf("got %d %q", status, body)
+		}
+	}
+
+	if n := cachedEntries(t, dir); n != 0 {
+		t.Fatalf("got %d cache entries, want 0", n)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Regular and early close of the US equity market, in exchange time
+const (
+	marketOpen       = 9*time.Hour + 30*time.Minute
+	marketClose      = 16 * time.Hour
+	marketEarlyClose = 13 * time.Hour
+)
+
+// Session describes one calendar day on the exchange
+type Session struct {
+	Date    time.Time
+	Open    bool
+	HalfDay bool
+
+	// Why the market is closed or closes early
+	Reason string
+
+	Opens  time.Time
+	Closes time.Time
+}
+
+// Calendar knows the US market holidays (NYSE rules) and half days. Extra
+// closures, e.g. a national day of mourning, can be added from the config.
+type Calendar struct {
+	loc   *time.Location
+	extra map[string]string
+}
+
+func NewCalendar(extraHolidays []string) (*Calendar, error) {
+	loc, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Calendar{loc: loc, extra: map[string]string{}}
+	for _, d := range extraHolidays {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q, want YYYY-MM-DD", d)
+		}
+		c.extra[d] = "Market closure"
+	}
+
+	return c, nil
+}
+
+// Session returns the session on t's day in exchange time
+func (c *Calendar) Session(t time.Time) Session {
+	t = t.In(c.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+	s := Session{Date: day}
+
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		s.Reason = "Weekend"
+		return s
+	}
+
+	if name, ok := c.extra[day.Format(time.DateOnly)]; ok {
+		s.Reason = name
+		return s
+	}
+
+	if name := usHoliday(day); name != "" {
+		s.Reason = name
+		return s
+	}
+
+	s.Open = true
+	s.Opens = day.Add(marketOpen)
+	s.Closes = day.Add(marketClose)
+
+	if name := usHalfDay(day); name != "" {
+		s.HalfDay = true
+		s.Reason = name
+		s.Closes = day.Add(marketEarlyClose)
+	}
+
+	return s
+}
+
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	return c.Session(t).Open
+}
+
+// Sessions returns the trading sessions from one day to another, both included.
+// Anything replaying history should walk these instead of every weekday.
+func (c *Calendar) Sessions(from, to time.Time) []Session {
+	var sessions []Session
+
+	from, to = from.In(c.loc), to.In(c.loc)
+	for d := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, c.loc); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if s := c.Session(d); s.Open {
+			sessions = append(sessions, s)
+		}
+	}
+
+	return sessions
+}
+
+// usHoliday names the full-day market holiday on day, if any
+func usHoliday(day time.Time) string {
+	y, m, d := day.Date()
+
+	switch {
+	// New Year's Day moves to Monday when on a Sunday, but the exchange stays
+	// open on the Friday before when it falls on a Saturday
+	case m == time.January && d == 1 && day.Weekday() != time.Saturday:
+		return "New Year's Day"
+	case m == time.January && d == 2 && day.Weekday() == time.Monday:
+		return "New Year's Day (observed)"
+	case sameDay(day, nthWeekday(y, time.January, time.Monday, 3)):
+		return "Martin Luther King Jr. Day"
+	case sameDay(day, nthWeekday(y, time.February, time.Monday, 3)):
+		return "Washington's Birthday"
+	case sameDay(day, easter(y).AddDate(0, 0, -2)):
+		return "Good Friday"
+	case sameDay(day, lastWeekday(y, time.May, time.Monday)):
+		return "Memorial Day"
+	case y >= 2022 && sameDay(day, observed(y, time.June, 19)):
+		return "Juneteenth"
+	case sameDay(day, observed(y, time.July, 4)):
+		return "Independence Day"
+	case sameDay(day, nthWeekday(y, time.September, time.Monday, 1)):
+		return "Labor Day"
+	case sameDay(day, nthWeekday(y, time.November, time.Thursday, 4)):
+		return "Thanksgiving Day"
+	case sameDay(day, observed(y, time.December, 25)):
+		return "Christmas Day"
+	}
+
+	return ""
+}
+
+// usHalfDay names the early close on day, if any
+func usHalfDay(day time.Time) string {
+	y, m, d := day.Date()
+	wd := day.Weekday()
+
+	// When July 4th or Christmas is on a Friday or weekend the day before is a
+	// holiday or a weekend itself, so only Monday to Thursday close early
+	earlyWeekday := wd >= time.Monday && wd <= time.Thursday
+
+	switch {
+	case m == time.July && d == 3 && earlyWeekday:
+		return "Independence Day eve"
+	case sameDay(day, nthWeekday(y, time.November, time.Thursday, 4).AddDate(0, 0, 1)):
+		return "Day after Thanksgiving"
+	case m == time.December && d == 24 && earlyWeekday:
+		return "Christmas Eve"
+	}
+
+	return ""
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// nthWeekday returns the n-th given weekday of the month
+func nthWeekday(year int, month time.Month, wd time.Weekday, n int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(wd) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+func lastWeekday(year int, month time.Month, wd time.Weekday) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(wd) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// observed moves a fixed-date holiday off the weekend: Saturday to Friday, Sunday to Monday
+func observed(year int, month time.Month, day int) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+
+	return t
+}
+
+// easter returns Easter Sunday (Gregorian, anonymous algorithm)
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Prints the coming sessions, handy to check the calendar before relying on it
+func calendarCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("calendar", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON config file")
+	days := fs.Int("days", 14, "number of days to show")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	cal, err := NewCalendar(cfg.Holidays)
+	if err != nil {
+		return err
+	}
+
+	day := time.Now().In(cal.loc)
+	for range *days {
+		s := cal.Session(day)
+
+		switch {
+		case !s.Open:
+			fmt.Printf("%s  closed      %s\n", s.Date.Format("Mon 2006-01-02"), s.Reason)
+		case s.HalfDay:
+			fmt.Printf("%s  %s-%s %s\n", s.Date.Format("Mon 2006-01-02"), s.Opens.Format("15:04"), s.Closes.Format("15:04"), s.Reason)
+		default:
+			fmt.Printf("%s  %s-%s\n", s.Date.Format("Mon 2006-01-02"), s.Opens.Format("15:04"), s.Closes.Format("15:04"))
+		}
+
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarSessions(t *testing.T) {
+	// 2025-01-09 was the national day of mourning for President Carter
+	cal, err := NewCalendar([]string{"2025-01-09"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date    string
+		open    bool
+		halfDay bool
+		reason  string
+	}{
+		{"2024-01-01", false, false, "New Year's Day"},
+		{"2024-01-15", false, false, "Martin Luther King Jr. Day"},
+		{"2024-02-19", false, false, "Washington's Birthday"},
+		{"2024-03-29", false, false, "Good Friday"},
+		{"2024-05-27", false, false, "Memorial Day"},
+		{"2024-06-19", false, false, "Juneteenth"},
+		{"2024-07-03", true, true, "Independence Day eve"},
+		{"2024-07-04", false, false, "Independence Day"},
+		{"2024-09-02", false, false, "Labor Day"},
+		{"2024-11-28", false, false, "Thanksgiving Day"},
+		{"2024-11-29", true, true, "Day after Thanksgiving"},
+		{"2024-12-24", true, true, "Christmas Eve"},
+		{"2024-12-25", false, false, "Christmas Day"},
+		{"2025-01-09", false, false, "Market closure"},
+		{"2025-04-18", false, false, "Good Friday"},
+		{"2025-03-10", true, false, ""},
+		{"2025-03-08", false, false, "Weekend"},
+
+		// Weekend holidays and their observed days
+		{"2021-12-31", true, false, ""},
+		{"2022-06-20", false, false, "Juneteenth"},
+		{"2021-06-18", true, false, ""},
+		{"2021-07-05", false, false, "Independence Day"},
+		{"2022-12-26", false, false, "Christmas Day"},
+		{"2023-01-02", false, false, "New Year's Day (observed)"},
+		{"2026-07-03", false, false, "Independence Day"},
+		{"2027-12-24", false, false, "Christmas Day"},
+	}
+
+	for _, tt := range tests {
+		day, _ := time.ParseInLocation(time.DateOnly, tt.date, cal.loc)
+		s := cal.Session(day.Add(8 * time.Hour))
+
+		if s.Open != tt.open || s.HalfDay != tt.halfDay || s.Reason != tt.reason {
+			t.Errorf("%s: got open=%v half=%v %q, want open=%v half=%v %q",
+				tt.date, s.Open, s.HalfDay, s.Reason, tt.open, tt.halfDay, tt.reason)
+		}
+
+		if s.HalfDay && s.Closes.Format("15:04") != "13:00" {
+			t.Errorf("%s: half day closes at %s", tt.date, s.Closes.Format("15:04"))
+		}
+	}
+}
+
+func TestCalendarSessionsBetween(t *testing.T) {
+	cal, err := NewCalendar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Christmas week 2024: Tuesday is a half day, Wednesday the holiday
+	from := time.Date(2024, 12, 21, 0, 0, 0, 0, cal.loc)
+	to := time.Date(2024, 12, 29, 0, 0, 0, 0, cal.loc)
+
+	var got []string
+	for _, s := range cal.Sessions(from, to) {
+		got = append(got, s.Date.Format(time.DateOnly))
+	}
+
+	want := []string{"2024-12-23", "2024-12-24", "2024-12-26", "2024-12-27"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// One recorded request/response pair. Request headers are left out on purpose,
+// they carry the API key and cassettes are meant to be shared.
+type interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	// Tells apart requests to the same URL that only differ in their body,
+	// like OpenFIGI lookups
+	BodySHA256 string `json:"bodySha256,omitempty"`
+
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+
+	// Set when the request never got a response, replay fails with the same message
+	Error string `json:"error,omitempty"`
+}
+
+// A cassette holds every outbound HTTP interaction of a run
+type cassette struct {
+	mu           sync.Mutex
+	path         string
+	Interactions []interaction `json:"interactions"`
+
+	// Replay position per request, so repeated calls to the same URL come back in order
+	played map[string]int
+}
+
+func loadCassette(path string) (*cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+
+	c := &cassette{path: path, played: map[string]int{}}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Rewind makes every interaction playable again, for commands that run the
+// pipeline more than once
+func (c *cassette) Rewind() {
+	c.mu.Lock()
+	c.played = map[string]int{}
+	c.mu.Unlock()
+}
+
+func (c *cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+
+	return nil
+}
+
+// recordingTransport passes requests through and appends every exchange to the cassette
+type recordingTransport struct {
+	cassette *cassette
+	next     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bodySum, err := requestBodySHA256(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.add(interaction{Method: req.Method, URL: req.URL.String(), BodySHA256: bodySum, Error: err.Error()})
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.add(interaction{Method: req.Method, URL: req.URL.String(), BodySHA256: bodySum, Error: err.Error()})
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.add(interaction{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		BodySHA256: bodySum,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       string(body),
+	})
+
+	return resp, nil
+}
+
+// requestBodySHA256 hashes the request body, if it has one, and leaves the
+// body readable for the actual request
+func requestBodySHA256(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(body) == 0 {
+		return "", nil
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (in interaction) key() string {
+	if in.BodySHA256 != "" {
+		return in.Method + " " + in.URL + " " + in.BodySHA256
+	}
+	return in.Method + " " + in.URL
+}
+
+func (t *recordingTransport) add(in interaction) {
+	t.cassette.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, in)
+	t.cassette.mu.Unlock()
+}
+
+// replayingTransport answers requests from the cassette and never touches the network
+type replayingTransport struct {
+	cassette *cassette
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.cassette
+
+	bodySum, err := requestBodySHA256(req)
+	if err != nil {
+		return nil, err
+	}
+	key := interaction{Method: req.Method, URL: req.URL.String(), BodySHA256: bodySum}.key()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Find the next unplayed interaction for this request
+	seen := 0
+	for _, in := range c.Interactions {
+		if in.key() != key {
+			continue
+		}
+
+		if seen == c.played[key] {
+			c.played[key]++
+			if in.Error != "" {
+				return nil, errors.New(in.Error)
+			}
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+				StatusCode:    in.StatusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        in.Header.Clone(),
+				Body:          io.NopCloser(bytes.NewReader([]byte(in.Body))),
+				ContentLength: int64(len(in.Body)),
+				Request:       req,
+			}, nil
+		}
+		seen++
+	}
+
+	return nil, fmt.Errorf("no recorded interaction for %s in %s", key, c.path)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestReplayReturnsRecordedError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := &cassette{path: path}
+
+	client := &http.Client{Transport: &recordingTransport{cassette: rec, next: failingTransport{}}}
+	_, recordErr := client.Get("https://example.com/news?id=AAPL")
+	if recordErr == nil {
+		t.Fatal("expected an error while recording")
+	}
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := loadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = &http.Client{Transport: &replayingTransport{cassette: c}}
+	_, replayErr := client.Get("https://example.com/news?id=AAPL")
+	if replayErr == nil || replayErr.Error() != recordErr.Error() {
+		t.Fatalf("replay error: got %v, want %v", replayErr, recordErr)
+	}
+}
+
+type echoTransport struct{}
+
+func (echoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestReplayMatchesRequestBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := &cassette{path: path}
+
+	// OpenFIGI lookups all go to one URL, only the body differs
+	const mapping = "https://api.openfigi.com/v3/mapping"
+	bodies := []string{`[{"idType":"ID_ISIN","idValue":"US0378331005"}]`, `[{"idType":"ID_ISIN","idValue":"CA7800871021"}]`}
+
+	client := &http.Client{Transport: &recordingTransport{cassette: rec, next: echoTransport{}}}
+	for _, body := range bodies {
+		resp, err := client.Post(mapping, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(got) != body {
+			t.Fatalf("recording lost the request body, got %q", got)
+		}
+	}
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := loadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replayed in the other order, as concurrent workers may
+	client = &http.Client{Transport: &replayingTransport{cassette: c}}
+	for i := len(bodies) - 1; i >= 0; i-- {
+		resp, err := client.Post(mapping, "application/json", strings.NewReader(bodies[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(got) != bodies[i] {
+			t.Errorf("got %q, want %q", got, bodies[i])
+		}
+	}
+
+	if _, err := client.Post(mapping, "application/json", strings.NewReader(`[]`)); err == nil {
+		t.Error("unrecorded body: want an error")
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Every command gets the remaining arguments after its name
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"run":      runCommand,
+	"watch":    watchCommand,
+	"daemon":   daemonCommand,
+	"calendar": calendarCommand,
+	"alert":    alertCommand,
+	"batch":    batchCommand,
+	"verify":   verifyCommand,
+	"history":  historyCommand,
+	"risk":     riskCommand,
+	"serve":    serveCommand,
+
+	"export-run":      exportRunCommand,
+	"install-service": installServiceCommand,
+}
+
+// Flags shared by every command that runs the pipeline
+type options struct {
+	configPath string
+	inputPath  string
+	outputPath string
+	cacheDir   string
+	noCache    bool
+	record     string
+	replay     string
+
+	ignoreWindow bool
+	report       bool
+	language     string
+	tags         listFlag
+	note         string
+	account      string
+	split        bool
+	review       bool
+	explain      bool
+
+	// Set by setup when replaying
+	replayed *cassette
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", "", "path to a JSON config file")
+	fs.StringVar(&o.inputPath, "input", "./opg.csv", "CSV file with ticker, gap and opening price")
+	fs.StringVar(&o.outputPath, "output", "./opg.json", "where to write the selections")
+	fs.StringVar(&o.cacheDir, "cache-dir", defaultCacheDir(), "directory for cached provider responses")
+	fs.BoolVar(&o.noCache, "no-cache", false, "disable the HTTP response cache")
+	fs.StringVar(&o.record, "record", "", "record all HTTP interactions to this cassette file")
+	fs.StringVar(&o.replay, "replay", "", "replay HTTP interactions from this cassette file instead of the network")
+	fs.BoolVar(&o.ignoreWindow, "ignore-window", false, "run even outside the configured run window")
+	fs.BoolVar(&o.report, "report", false, "also write a Markdown report next to the output")
+	fs.StringVar(&o.language, "lang", "", "report language (en, de, es), the config's language by default")
+	fs.Var(&o.tags, "tag", "label stored with the run, e.g. fomc-day, repeatable")
+	fs.StringVar(&o.note, "note", "", "freeform note stored with the run")
+	fs.StringVar(&o.account, "account", "", "size for this account from the config")
+	fs.BoolVar(&o.split, "split", false, "write one plan per configured account, sized for each")
+	fs.BoolVar(&o.explain, "explain", false, "add the math behind each position to the plan")
+	fs.BoolVar(&o.review, "review", false, "approve, reject or resize each selection before it's delivered")
+}
+
+// Repeatable string flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return errors.New("must not be empty")
+	}
+	*l = append(*l, v)
+	return nil
+}
+
+// setup loads the config and builds the engine. The returned func has to be
+// called once the engine is no longer used, it saves the cassette when recording.
+func (o *options) setup() (*Engine, func(), error) {
+	cfg, err := LoadConfig(o.configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.record != "" && o.replay != "" {
+		return nil, nil, errors.New("-record and -replay can't be used together")
+	}
+
+	if o.account != "" {
+		if o.split {
+			return nil, nil, errors.New("-account and -split can't be used together")
+		}
+		if cfg, err = cfg.forAccount(o.account); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Shared by all provider calls so they go through the same transport (cache etc.)
+	client := &http.Client{}
+	done := func() {}
+
+	if !o.noCache && o.replay == "" {
+		transport, err := newCachingTransport(o.cacheDir, http.DefaultTransport)
+		if err != nil {
+			return nil, nil, err
+		}
+		client.Transport = transport
+	}
+
+	// Recording sits in front of the cache so the cassette holds exactly what the program saw
+	if o.record != "" {
+		rec := &cassette{path: o.record}
+		next := client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		client.Transport = &recordingTransport{cassette: rec, next: next}
+
+		done = func() {
+			if err := rec.Save(); err != nil {
+				log.Printf("Error saving cassette, %v", err)
+				return
+			}
+			log.Printf("Recorded %d interactions to %s\n", len(rec.Interactions), o.record)
+		}
+	}
+
+	if o.replay != "" {
+		c, err := loadCassette(o.replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		client.Transport = &replayingTransport{cassette: c}
+		o.replayed = c
+	}
+
+	engine := NewEngine(cfg, client)
+	engine.explain = o.explain
+
+	return engine, done, nil
+}
+
+// rewind starts the replay cassette over, so every pass of a repeating command
+// sees the same recorded run
+func (o *options) rewind() {
+	if o.replayed != nil {
+		o.replayed.Rewind()
+	}
+}
+
+// runOnce does one full pass over the input, delivers the selections and
+// records the run summary
+func runOnce(ctx context.Context, engine *Engine, opts *options, inputPath, outputPath string) (*Summary, error) {
+	if opts.split {
+		// Each account's summary is in its journal, the last one is returned
+		first := true
+		return splitAccounts(engine, func(engine *Engine, outputPath string) (*Summary, error) {
+			if !first {
+				opts.rewind()
+			}
+			first = false
+			return runPlan(ctx, engine, opts, inputPath, outputPath)
+		}, outputPath)
+	}
+
+	return runPlan(ctx, engine, opts, inputPath, outputPath)
+}
+
+func runPlan(ctx context.Context, engine *Engine, opts *options, inputPath, outputPath string) (*Summary, error) {
+	enc := engine.Config().Encryption
+	if err := enc.check(); err != nil {
+		return nil, err
+	}
+
+	// A manual run while the daemon is at it would deliver the plan twice
+	lock, err := acquireLock(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	// Kept with the run for export-run
+	logs, stopLogs := runLogs.capture()
+	defer stopLogs()
+
+	// Output the results as they come in
+	file, err := NewJSONFileSink(outputPath, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	// The report can't read an encrypted plan back, it gets a copy instead
+	var sink Sink = file
+	var kept *keepingSink
+	if opts.report {
+		kept = &keepingSink{Sink: file}
+		sink = kept
+	}
+
+	// Reviewed in front of the report so it only shows what was delivered
+	var review *reviewSink
+	if opts.review {
+		l, err := opts.locale(engine)
+		if err != nil {
+			file.Abort()
+			return nil, err
+		}
+		review = newReviewSink(sink, engine, l, os.Stdin, os.Stdout)
+		sink = review
+	}
+
+	summary, err := engine.Run(ctx, inputPath, sink)
+	if err != nil {
+		// Keep whatever plan was delivered last
+		sink.Abort()
+		return summary, err
+	}
+
+	if err := sink.Close(); err != nil {
+		return summary, err
+	}
+
+	if review != nil {
+		// Selected counts what was delivered
+		summary.Reviews = review.decisions
+		summary.Selected -= review.rejected()
+	}
+
+	summary.Output = file.Path()
+	summary.Tags, summary.Note = opts.tags, opts.note
+	summary.Account = engine.Config().account
+	summary.Log()
+
+	if _, err := summary.Save(engine.Config().RunsDir); err != nil {
+		log.Printf("Error saving run summary, %v", err)
+	}
+
+	log.Printf("Finished writing output to %s\n", file.Path())
+
+	outputs := []string{file.Path()}
+
+	meta, err := writeOutputMeta(metaPath(outputPath), summary, enc)
+	if err != nil {
+		return summary, err
+	}
+	outputs = append(outputs, meta)
+
+	if kept != nil {
+		path, err := writeReport(engine, opts, reportPath(outputPath), summary, kept.selections)
+		if err != nil {
+			return summary, err
+		}
+		outputs = append(outputs, path)
+	}
+
+	if m := engine.Config().Manifest; m.Enabled {
+		path := manifestPath(outputPath)
+		if err := writeManifest(path, outputs, m.SigningKey); err != nil {
+			return summary, err
+		}
+		log.Printf("Wrote manifest %s", path)
+
+		outputs = append(outputs, path)
+		if m.SigningKey != "" {
+			outputs = append(outputs, path+".sig")
+		}
+	}
+
+	// Other runs of a batch may still be logging
+	stopLogs()
+	dir := runFilesDir(engine.Config().RunsDir, summary.ID)
+	if err := saveRunFiles(dir, engine.Config(), inputPath, outputs, logs.Bytes()); err != nil {
+		log.Printf("Error keeping the files of run %s, %v", summary.ID, err)
+	}
+
+	return summary, nil
+}
+
+// keepingSink passes selections on and keeps a copy for the report
+type keepingSink struct {
+	Sink
+	selections []Selection
+}
+
+func (s *keepingSink) Write(sel Selection) error {
+	if err := s.Sink.Write(sel); err != nil {
+		return err
+	}
+	s.selections = append(s.selections, sel)
+	return nil
+}
+
+// locale is the language of the -lang flag, the config's otherwise
+func (o *options) locale(engine *Engine) (*Locale, error) {
+	lang := o.language
+	if lang == "" {
+		lang = engine.Config().Language
+	}
+	return LoadLocale(lang)
+}
+
+// writeReport renders the delivered plan in the requested language
+func writeReport(engine *Engine, opts *options, path string, summary *Summary, selections []Selection) (string, error) {
+	l, err := opts.locale(engine)
+	if err != nil {
+		return "", err
+	}
+
+	path, err = writeReportFile(path, l, engine, summary, selections)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Wrote %s report to %s", l.Language, path)
+	return path, nil
+}
+
+func runCommand(ctx context.Context, args []string) error {
+	var opts options
+
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	opts.register(fs)
+	fs.Parse(args)
+
+	engine, done, err := opts.setup()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if !opts.ignoreWindow {
+		if err := engine.Config().checkWindow(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	_, err = runOnce(ctx, engine, &opts, opts.inputPath, opts.outputPath)
+	return err
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronExpr is a parsed five-field cron expression: minute hour day-of-month month day-of-week
+type cronExpr struct {
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+
+	// Standard cron semantics: when both day fields are restricted a day
+	// matches if either of them does
+	domAny bool
+	dowAny bool
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+func parseCron(spec string) (*cronExpr, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields, got %d", spec, len(fields))
+	}
+
+	c := &cronExpr{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+
+	if err := parseCronField(fields[0], 0, 59, nil, c.minute[:]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: minute: %w", spec, err)
+	}
+	if err := parseCronField(fields[1], 0, 23, nil, c.hour[:]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: hour: %w", spec, err)
+	}
+	if err := parseCronField(fields[2], 1, 31, nil, c.dom[:]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: day of month: %w", spec, err)
+	}
+	if err := parseCronField(fields[3], 1, 12, monthNames, c.month[:]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: month: %w", spec, err)
+	}
+
+	// 7 is accepted as an alias for Sunday
+	var dow [8]bool
+	if err := parseCronField(fields[4], 0, 7, dayNames, dow[:]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: day of week: %w", spec, err)
+	}
+	copy(c.dow[:], dow[:7])
+	c.dow[0] = c.dow[0] || dow[7]
+
+	return c, nil
+}
+
+// parseCronField sets set[n] for every value the field matches. Supports *,
+// lists, ranges, steps and names.
+func parseCronField(field string, min, max int, names map[string]int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if base, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step %q", s)
+			}
+			step = n
+			part = base
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+
+			var err error
+			if lo, err = cronValue(from, names); err != nil {
+				return err
+			}
+
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(to, names); err != nil {
+					return err
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting at 5
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return nil
+}
+
+func cronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	return v, nil
+}
+
+func (c *cronExpr) dayMatches(t time.Time) bool {
+	dom := c.dom[t.Day()]
+	dow := c.dow[t.Weekday()]
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first time strictly after t that matches, in t's location.
+// The zero time is returned if nothing matches within five years (e.g. "0 0 30 2 *").
+func (c *cronExpr) Next(t time.Time) time.Time {
+	loc := t.Location()
+	start := t
+
+	// Round in absolute time, rebuilding the wall clock would pick the first of
+	// two identical times on the fall-back day and could land before t
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.month[t.Month()] {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		if !c.hour[t.Hour()] {
+			// Moving in absolute time keeps DST transitions from sending us back an hour
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+
+		if !c.minute[t.Minute()] || !t.After(start) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// forward returns next unless a DST switch made it land at or before t (a
+// midnight that doesn't exist), then it just moves on by a minute
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loc
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec  string
+		check func(c *cronExpr) bool
+	}{
+		{"0,15,45 * * * *", func(c *cronExpr) bool { return c.minute[0] && c.minute[15] && c.minute[45] && !c.minute[30] }},
+		{"* 8-10 * * *", func(c *cronExpr) bool { return !c.hour[7] && c.hour[8] && c.hour[10] && !c.hour[11] }},
+		{"*/20 * * * *", func(c *cronExpr) bool { return c.minute[0] && c.minute[20] && c.minute[40] && !c.minute[10] }},
+		{"5/20 * * * *", func(c *cronExpr) bool { return c.minute[5] && c.minute[25] && c.minute[45] && !c.minute[0] }},
+		{"0 9-17/4 * * *", func(c *cronExpr) bool { return c.hour[9] && c.hour[13] && c.hour[17] && !c.hour[10] }},
+		{"* * * jan,Mar-may *", func(c *cronExpr) bool { return c.month[1] && !c.month[2] && c.month[3] && c.month[5] && !c.month[6] }},
+		{"* * * * MON-fri", func(c *cronExpr) bool { return !c.dow[0] && c.dow[1] && c.dow[5] && !c.dow[6] }},
+		{"* * * * 7", func(c *cronExpr) bool { return c.dow[0] && !c.dow[6] }},
+		{"* * * * 5-7", func(c *cronExpr) bool { return c.dow[0] && c.dow[5] && c.dow[6] && !c.dow[1] }},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCron(tt.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if !tt.check(c) {
+			t.Errorf("%q: parsed fields don't match", tt.spec)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+
+	// On the fall-back day 01:00-01:59 happens twice, once in EDT and once in EST
+	edt := time.FixedZone("EDT", -4*3600)
+	est := time.FixedZone("EST", -5*3600)
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"weekday skips weekend", "20 9 * * 1-5", time.Date(2024, 3, 8, 9, 20, 0, 0, ny), time.Date(2024, 3, 11, 9, 20, 0, 0, ny)},
+		{"same day later", "20 9 * * 1-5", time.Date(2024, 3, 8, 9, 19, 30, 0, ny), time.Date(2024, 3, 8, 9, 20, 0, 0, ny)},
+		{"steps within hours", "*/15 8-9 * * *", time.Date(2024, 3, 8, 9, 45, 0, 0, ny), time.Date(2024, 3, 9, 8, 0, 0, 0, ny)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, ny), time.Date(2028, 2, 29, 0, 0, 0, 0, ny)},
+		{"never", "0 0 30 2 *", time.Date(2024, 1, 1, 0, 0, 0, 0, ny), time.Time{}},
+
+		// Both day fields restricted: the 1st of the month or any Monday
+		{"dom or dow hits monday", "0 12 1 * 1", time.Date(2024, 3, 2, 0, 0, 0, 0, ny), time.Date(2024, 3, 4, 12, 0, 0, 0, ny)},
+		{"dom or dow hits the 1st", "0 12 1 * 1", time.Date(2024, 3, 26, 0, 0, 0, 0, ny), time.Date(2024, 4, 1, 12, 0, 0, 0, ny)},
+		{"dom only", "0 12 1 * *", time.Date(2024, 3, 2, 0, 0, 0, 0, ny), time.Date(2024, 4, 1, 12, 0, 0, 0, ny)},
+
+		// Spring forward: 02:30 doesn't exist on 2024-03-10
+		{"spring forward skips missing time", "30 2 * * *", time.Date(2024, 3, 9, 2, 30, 0, 0, ny), time.Date(2024, 3, 11, 2, 30, 0, 0, ny)},
+		{"spring forward hourly", "0 * * * *", time.Date(2024, 3, 10, 1, 0, 0, 0, ny), time.Date(2024, 3, 10, 3, 0, 0, 0, ny)},
+
+		// Fall back: 01:30 exists twice on 2024-11-03
+		{"fall back first 01:30", "30 1 * * *", time.Date(2024, 11, 3, 1, 0, 0, 0, edt).In(ny), time.Date(2024, 11, 3, 1, 30, 0, 0, edt)},
+		{"fall back second 01:30", "30 1 * * *", time.Date(2024, 11, 3, 1, 30, 0, 0, edt).In(ny), time.Date(2024, 11, 3, 1, 30, 0, 0, est)},
+		{"fall back moves on", "30 1 * * *", time.Date(2024, 11, 3, 1, 30, 0, 0, est).In(ny), time.Date(2024, 11, 4, 1, 30, 0, 0, ny)},
+		{"fall back every 5 minutes", "*/5 * * * *", time.Date(2024, 11, 3, 1, 55, 0, 0, est).In(ny), time.Date(2024, 11, 3, 2, 0, 0, 0, ny)},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		got := c.Next(tt.from)
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: Next(%s) = %s, want %s", tt.name, tt.from, got, tt.want.In(ny))
+		}
+		if !got.IsZero() && !got.After(tt.from) {
+			t.Errorf("%s: Next(%s) = %s is not after the input", tt.name, tt.from, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed ui/dashboard.html
+var dashboardHTML string
+
+// Formatting funcs are bound per request, these only make the template parse
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"price": func(string, float64) string { return "" },
+	"money": func(float64) string { return "" },
+	"pct":   func(float64) string { return "" },
+	"date":  func(time.Time) string { return "" },
+	"ago":   func(time.Time) string { return "" },
+	"t":     func(string) string { return "" },
+	"lang":  func() string { return "" },
+}).Parse(dashboardHTML))
+
+// dashboard serves the latest plan and the run history, read from disk on
+// every request so it always shows the newest run
+type dashboard struct {
+	engine   *Engine
+	locale   *Locale
+	planPath string
+}
+
+// A selection with what it puts at risk, one row of the plan table
+type planRow struct {
+	Selection
+	Side string
+	Risk float64
+}
+
+type dashboardPage struct {
+	Account  string
+	Latest   *Summary
+	Rows     []planRow
+	Exposure Exposure
+	History  []*Summary
+
+	// Why there's no plan to show, the table is empty then
+	PlanError string
+
+	Now time.Time
+}
+
+func (d *dashboard) page() (*dashboardPage, error) {
+	cfg := d.engine.Config()
+
+	history, err := loadSummaries(cfg.RunsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dashboardPage{Account: cfg.account, Now: time.Now()}
+	if len(history) > 0 {
+		p.Latest = history[0]
+	}
+	p.History = history[:min(len(history), 30)]
+
+	// An encrypted plan can't be shown, say why instead of failing the page
+	selections, err := ReadSelections(d.planPath)
+	if err != nil {
+		p.PlanError = err.Error()
+		return p, nil
+	}
+
+	for _, sel := range selections {
+		row := planRow{Selection: sel, Side: "SHORT", Risk: d.engine.risk(sel)}
+		if sel.Long() {
+			row.Side = "LONG"
+		}
+		p.Rows = append(p.Rows, row)
+	}
+	p.Exposure = d.engine.Exposure(selections)
+
+	return p, nil
+}
+
+func (d *dashboard) serveUI(w http.ResponseWriter, r *http.Request) {
+	p, err := d.page()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	e, l := d.engine, d.locale
+	t := template.Must(dashboardTemplate.Clone()).Funcs(template.FuncMap{
+		"price": func(ticker string, v float64) string { return e.formatPrice(l, ticker, v) },
+		"money": func(v float64) string { return e.formatMoney(l, v) },
+		"pct": func(v float64) string {
+			if p.Exposure.Balance == 0 {
+				return ""
+			}
+			return l.Number(100*v/p.Exposure.Balance, 1) + "%"
+		},
+		"date": l.Date,
+		"ago":  func(t time.Time) string { return l.Ago(t, p.Now) },
+		"t":    l.T,
+		"lang": func() string { return strings.ReplaceAll(l.Language, "_", "-") },
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, p); err != nil {
+		log.Printf("Error rendering the dashboard, %v", err)
+	}
+}
+
+func (d *dashboard) Handler(ui bool) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+
+	// The same data as JSON, for scripts and other tools
+	mux.HandleFunc("GET /api/plan", func(w http.ResponseWriter, r *http.Request) {
+		selections, err := ReadSelections(d.planPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, selections)
+	})
+	mux.HandleFunc("GET /api/runs", func(w http.ResponseWriter, r *http.Request) {
+		summaries, err := loadSummaries(d.engine.Config().RunsDir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, summaries)
+	})
+
+	if ui {
+		mux.HandleFunc("GET /{$}", d.serveUI)
+	}
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Serves the latest plan and the run history over HTTP, with -ui as a web
+// page to check the plan from a phone
+func serveCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON config file")
+	account := fs.String("account", "", "serve the plan and journal of this account")
+	planPath := fs.String("plan", "./opg.json", "plan to show, as written by run")
+	listen := fs.String("listen", "localhost:8080", "address to serve on, :8080 to reach it from other machines")
+	ui := fs.Bool("ui", false, "serve the dashboard at /, not only the JSON API")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *account != "" {
+		if cfg, err = cfg.forAccount(*account); err != nil {
+			return err
+		}
+	}
+
+	l, err := LoadLocale(cfg.Language)
+	if err != nil {
+		return err
+	}
+
+	d := &dashboard{engine: NewEngine(cfg, nil), locale: l, planPath: *planPath}
+
+	if *ui {
+		log.Printf("Serving the dashboard on http://%s/", *listen)
+	} else {
+		log.Printf("Serving /api/plan and /api/runs on %s", *listen)
+	}
+
+	return serve(ctx, *listen, d.Handler(*ui))
+}
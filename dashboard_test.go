@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDashboard(t *testing.T) *dashboard {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := DefaultConfig()
+	cfg.RunsDir = filepath.Join(dir, "runs")
+	summary := &Summary{ID: "20240115-092012-a1b2c3", StartedAt: time.Now(), FinishedAt: time.Now(), Input: "opg.csv", Selected: 2, Failures: []Failure{}, Tags: []string{"earnings"}}
+	if _, err := summary.Save(cfg.RunsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	selections := []Selection{
+		{Ticker: "MSFT", Position: Position{EntryPrice: 108.86, Shares: 13, TakeProfitPrice: 94.35, StopLossPrice: 123.37, Profit: 188.63},
+			Articles: []Article{{PublishOn: time.Date(2024, 7, 9, 19, 35, 0, 0, time.UTC), Headline: "MSFT <beats> estimates"}}},
+		{Ticker: "AMZN", Position: Position{EntryPrice: 160.79, Shares: 9, TakeProfitPrice: 181.73, StopLossPrice: 139.85, Profit: 188.46},
+			Status: StatusError, Error: "loading news: unsuccessful status code 429"},
+	}
+	data, _ := json.Marshal(selections)
+	planPath := filepath.Join(dir, "opg.json")
+	if err := os.WriteFile(planPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := LoadLocale("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &dashboard{engine: NewEngine(cfg, nil), locale: l, planPath: planPath}
+}
+
+func getPage(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func TestDashboardUI(t *testing.T) {
+	d := testDashboard(t)
+
+	code, body := getPage(t, d.Handler(true), "/")
+	if code != http.StatusOK {
+		t.Fatalf("got status %d:\n%s", code, body)
+	}
+
+	for _, want := range []string{
+		"Run 20240115-092012-a1b2c3",
+		"earnings",
+		`<td data-v="108.86" class="num">$108.86</td>`,
+		// Risk of MSFT, 14.51 a share
+		"$188.63",
+		"MSFT &lt;beats&gt; estimates",
+		"unsuccessful status code 429",
+		// Both stops hit
+		"Worst case <b>$377.09</b> 3.8%",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+}
+
+func TestDashboardWithoutPlan(t *testing.T) {
+	d := testDashboard(t)
+	d.planPath = filepath.Join(t.TempDir(), "missing.json")
+
+	code, body := getPage(t, d.Handler(true), "/")
+	if code != http.StatusOK || !strings.Contains(body, "No plan to show") {
+		t.Errorf("got status %d, want the page to say there's no plan:\n%s", code, body)
+	}
+}
+
+func TestDashboardAPI(t *testing.T) {
+	h := testDashboard(t).Handler(false)
+
+	if code, _ := getPage(t, h, "/"); code != http.StatusNotFound {
+		t.Errorf("got status %d for / without -ui, want 404", code)
+	}
+
+	_, body := getPage(t, h, "/api/plan")
+	var selections []Selection
+	if err := json.Unmarshal([]byte(body), &selections); err != nil || len(selections) != 2 {
+		t.Errorf("got %d selections, %v", len(selections), err)
+	}
+
+	_, body = getPage(t, h, "/api/runs")
+	var runs []Summary
+	if err := json.Unmarshal([]byte(body), &runs); err != nil || len(runs) != 1 {
+		t.Errorf("got %d runs, %v", len(runs), err)
+	}
+}
+
+func TestDashboardRowRiskCountsScaleIn(t *testing.T) {
+	d := testDashboard(t)
+
+	data, _ := json.Marshal([]Selection{{
+		Ticker:   "X",
+		Position: Position{EntryPrice: 100, Shares: 20, TakeProfitPrice: 108, StopLossPrice: 92},
+		Orders: []Order{
+			{Shares: 10, StopLossPrice: 92},
+			{Shares: 10, TriggerPrice: 102, StopLossPrice: 100},
+		},
+	}})
+	if err := os.WriteFile(d.planPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := d.page()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Rows) != 1 || p.Rows[0].Risk != 100 || p.Rows[0].Risk != p.Exposure.WorstCase() {
+		t.Errorf("got rows %+v, want the risk of 100 the exposure counts", p.Rows)
+	}
+}
+
+func TestDashboardLocalized(t *testing.T) {
+	d := testDashboard(t)
+	de, err := LoadLocale("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.locale = de
+
+	_, body := getPage(t, d.Handler(true), "/")
+
+	for _, want := range []string{
+		`<html lang="de">`,
+		"Lauf 20240115-092012-a1b2c3",
+		"2 ausgewählt, 0 fehlgeschlagen",
+		"Schlimmster Fall <b>377,09 $</b> 3,8% des Kontostands",
+		"<th data-sort>Richtung</th>",
+		"1 Artikel",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard missing %q", want)
+		}
+	}
+	for _, english := range []string{"Worst case", "Headlines", "refreshes every minute"} {
+		if strings.Contains(body, english) {
+			t.Errorf("dashboard still says %q", english)
+		}
+	}
+}
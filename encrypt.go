@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Encryption of the output files, which hold the account size and are often
+// synced through cloud storage. Recipients are age public keys or GPG key IDs,
+// the files are encrypted by the age or gpg binary on the PATH.
+type Encryption struct {
+	Age []string `json:"age,omitempty"`
+	GPG []string `json:"gpg,omitempty"`
+}
+
+func (e Encryption) Validate() error {
+	if len(e.Age) > 0 && len(e.GPG) > 0 {
+		return errors.New("encryption: set either age or gpg recipients, not both")
+	}
+	return nil
+}
+
+func (e Encryption) Enabled() bool {
+	return len(e.Age) > 0 || len(e.GPG) > 0
+}
+
+// Ext is appended to the name of encrypted files
+func (e Encryption) Ext() string {
+	switch {
+	case len(e.Age) > 0:
+		return ".age"
+	case len(e.GPG) > 0:
+		return ".gpg"
+	}
+	return ""
+}
+
+func (e Encryption) command() *exec.Cmd {
+	if len(e.Age) > 0 {
+		args := []string{}
+		for _, r := range e.Age {
+			args = append(args, "-r", r)
+		}
+		return exec.Command("age", args...)
+	}
+
+	// The recipients come from the config, so don't ask whether to trust them
+	args := []string{"--batch", "--yes", "--trust-model", "always", "--encrypt"}
+	for _, r := range e.GPG {
+		args = append(args, "--recipient", r)
+	}
+	return exec.Command("gpg", args...)
+}
+
+// encryptedFile pipes everything written to it through the encryption
+// binary into file, so the plain text never touches the disk
+type encryptedFile struct {
+	file  *os.File
+	stdin io.WriteCloser
+	cmd   *exec.Cmd
+}
+
+func newEncryptedFile(file *os.File, e Encryption) (*encryptedFile, error) {
+	cmd := e.command()
+	cmd.Stdout = file
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting %s: %w", cmd.Path, err)
+	}
+
+	return &encryptedFile{file: file, stdin: stdin, cmd: cmd}, nil
+}
+
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	return f.stdin.Write(p)
+}
+
+func (f *encryptedFile) Close() error {
+	f.stdin.Close()
+	err := f.cmd.Wait()
+
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error encrypting %s: %w", f.file.Name(), err)
+	}
+
+	return nil
+}
+
+// createOutput opens path for writing, through the encryption if enabled.
+// It returns the name the file actually gets.
+func createOutput(path string, e Encryption) (io.WriteCloser, string, error) {
+	path += e.Ext()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !e.Enabled() {
+		return f, path, nil
+	}
+
+	w, err := newEncryptedFile(f, e)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, "", err
+	}
+
+	return w, path, nil
+}
+
+// Checks that the binary the config asks for is installed before a run starts
+func (e Encryption) check() error {
+	if !e.Enabled() {
+		return nil
+	}
+
+	name := e.command().Args[0]
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("encryption needs %s on the PATH: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeAge puts an "age" on the PATH that prints its arguments and then
+// copies its input, which is enough to see what reached the encrypter
+func fakeAge(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script on the PATH")
+	}
+
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\"\nexec " + cat + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestJSONFileSinkEncrypts(t *testing.T) {
+	fakeAge(t)
+
+	enc := Encryption{Age: []string{"age1alice", "age1bob"}}
+	if err := enc.check(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "opg.json")
+	sink, err := NewJSONFileSink(path, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(Selection{Ticker: "MSFT", Status: StatusOK}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("plain %s exists next to the encrypted plan", path)
+	}
+
+	data, err := os.ReadFile(path + ".age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.HasPrefix(got, "-r age1alice -r age1bob\n") || !strings.Contains(got, `"Ticker":"MSFT"`) {
+		t.Errorf("encrypter got unexpected input:\n%s", got)
+	}
+
+	// Nothing left behind in the directory but the plan
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("got %d files, want only the encrypted plan", len(entries))
+	}
+}
+
+func TestEncryptionChecks(t *testing.T) {
+	if err := (Encryption{Age: []string{"a"}, GPG: []string{"b"}}).Validate(); err == nil {
+		t.Error("want error for age and gpg recipients together")
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if err := (Encryption{GPG: []string{"ops@example.com"}}).check(); err == nil {
+		t.Error("want error when gpg is not installed")
+	}
+}
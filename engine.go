@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Config holds everything a run depends on. It is passed explicitly instead of
+// living in package-level variables so several engines can run side by side.
+type Config struct {
+	// Money in the trading account
+	AccountBalance float64 `json:"accountBalance"`
+
+	// Percentage of balance i can tolerate losing
+	LossTolerance float64 `json:"lossTolerance"`
+
+	// Percentage of gap i want to take as profit
+	ProfitPercent float64 `json:"profitPercent"`
+
+	// Sector per ticker, and the part of the balance all stops together may
+	// lose before the risk command fails (0 doesn't check)
+	Sectors          map[string]string `json:"sectors,omitempty"`
+	MaxPortfolioRisk float64           `json:"maxPortfolioRisk,omitempty"`
+
+	// Accounts to choose from with -account or split the plan across with -split
+	Accounts []Account `json:"accounts,omitempty"`
+
+	// How many news requests may be in flight at once
+	Concurrency int `json:"concurrency"`
+
+	// Where run summaries are kept
+	RunsDir string `json:"runsDir"`
+
+	// Cron schedules for the daemon command
+	Schedules []Schedule `json:"schedules,omitempty"`
+
+	// Extra market closures (YYYY-MM-DD) on top of the regular US holidays
+	Holidays []string `json:"holidays,omitempty"`
+
+	// When runs are allowed, nil allows any time
+	RunWindow *RunWindow `json:"runWindow,omitempty"`
+
+	// Entry alerts for the alert command
+	Alerts AlertConfig `json:"alerts"`
+
+	// Rounding mode and tick sizes for prices
+	Rounding Rounding `json:"rounding"`
+
+	// Timezone article times are shown in, exchange time by default
+	DisplayTimezone string `json:"displayTimezone,omitempty"`
+
+	// Futures and forex pairs by symbol, everything else is a stock
+	Instruments map[string]Instrument `json:"instruments,omitempty"`
+
+	// Exchanges by ticker suffix, on top of the built-in ones (.TO, .L, ...)
+	Venues map[string]Venue `json:"venues,omitempty"`
+
+	// Currency of the balance, USD by default, and what one unit of other
+	// currencies is worth in it, for forex pairs quoted in them
+	AccountCurrency string             `json:"accountCurrency,omitempty"`
+	Rates           map[string]float64 `json:"rates,omitempty"`
+
+	// Staged entries, the whole position is entered at the open when nil
+	ScaleIn *ScaleIn `json:"scaleIn,omitempty"`
+
+	// Recipients the output files are encrypted for, plain files when empty
+	Encryption Encryption `json:"encryption"`
+
+	// Checksum manifest, optionally signed, next to the outputs
+	Manifest ManifestConfig `json:"manifest"`
+
+	// Mapping between tickers and ISIN/CUSIP/FIGI
+	Identifiers IdentifierConfig `json:"identifiers"`
+
+	// Precision and currencies of the numbers in reports
+	Formatting Formatting `json:"formatting"`
+
+	// Language of the reports (en, de, es)
+	Language string `json:"language,omitempty"`
+
+	// RapidAPI key for the Seeking Alpha API, RAPIDAPI_KEY in the environment wins
+	APIKey string `json:"apiKey,omitempty"`
+
+	// Name of the account the settings were taken from, if any
+	account string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		AccountBalance: 10000.0,
+		LossTolerance:  .02,
+		ProfitPercent:  .8,
+		Concurrency:    8,
+		RunsDir:        "./runs",
+	}
+}
+
+// LoadConfig reads a JSON config file on top of the defaults. An empty path
+// just returns the defaults.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("error reading config: %w", err)
+		}
+
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("error decoding config %s: %w", path, err)
+		}
+	}
+
+	if key := os.Getenv("RAPIDAPI_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+	if key := os.Getenv("OPENFIGI_KEY"); key != "" {
+		cfg.Identifiers.OpenFIGIKey = key
+	}
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" && cfg.Alerts.Notify.Telegram != nil {
+		cfg.Alerts.Notify.Telegram.BotToken = token
+	}
+	if webhook := os.Getenv("SLACK_WEBHOOK_URL"); webhook != "" {
+		cfg.Alerts.Notify.SlackWebhook = webhook
+	}
+
+	// The environment can hold secret references too
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	if err := cfg.resolveSecrets(ctx, newSecretResolver()); err != nil {
+		return cfg, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func (c Config) Validate() error {
+	if c.AccountBalance <= 0 {
+		return fmt.Errorf("accountBalance must be positive, got %v", c.AccountBalance)
+	}
+
+	if c.LossTolerance <= 0 || c.LossTolerance >= 1 {
+		return fmt.Errorf("lossTolerance must be between 0 and 1, got %v", c.LossTolerance)
+	}
+
+	if c.ProfitPercent <= 0 {
+		return fmt.Errorf("profitPercent must be positive, got %v", c.ProfitPercent)
+	}
+
+	if err := validateAccounts(c.Accounts); err != nil {
+		return err
+	}
+
+	for suffix, v := range c.Venues {
+		if err := v.Validate(suffix); err != nil {
+			return err
+		}
+	}
+
+	for symbol, in := range c.Instruments {
+		if err := in.Validate(symbol); err != nil {
+			return err
+		}
+		if in.Type == instrumentForex {
+			if _, err := c.quoteRate(symbol, 1); err != nil {
+				return fmt.Errorf("instruments.%s: %w", symbol, err)
+			}
+		}
+	}
+
+	if c.ScaleIn != nil {
+		if err := c.ScaleIn.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Rounding.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Encryption.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := c.displayLocation(); err != nil {
+		return fmt.Errorf("displayTimezone: %w", err)
+	}
+
+	if c.RunWindow != nil {
+		if err := c.RunWindow.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c Config) displayLocation() (*time.Location, error) {
+	if c.DisplayTimezone == "" {
+		return time.LoadLocation(defaultTimezone)
+	}
+	return time.LoadLocation(c.DisplayTimezone)
+}
+
+// Engine sizes positions and fetches news for one configuration
+type Engine struct {
+	cfg     Config
+	client  *http.Client
+	display *time.Location
+	ids     *idMapper
+
+	// Whether selections carry the math behind their position
+	explain bool
+}
+
+func NewEngine(cfg Config, client *http.Client) *Engine {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	// Validate has already checked the timezone of a loaded config
+	display, err := cfg.displayLocation()
+	if err != nil {
+		display = time.UTC
+	}
+
+	return &Engine{cfg: cfg, client: client, display: display, ids: newIDMapper(cfg.Identifiers, client)}
+}
+
+func (e *Engine) Config() Config {
+	return e.cfg
+}
+
+// DisplayLocation is the timezone times are shown in
+func (e *Engine) DisplayLocation() *time.Location {
+	return e.display
+}
+
+// Max amount i can tolerate losing, derived from the current balance every time
+func (e *Engine) MaxLossPerTrade() float64 {
+	return e.cfg.AccountBalance * e.cfg.LossTolerance
+}
+
+// Duration reads and writes durations as strings like "15m" in config files
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %w", err)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, json string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(json), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	t.Setenv("RAPIDAPI_KEY", "")
+
+	for _, path := range []string{"", writeConfig(t, `{"apiKey": "from-file"}`)} {
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.AccountBalance != 10000 || cfg.LossTolerance != .02 || cfg.ProfitPercent != .8 || cfg.Concurrency != 8 || cfg.RunsDir != "./runs" {
+			t.Errorf("%q: got %+v, want the defaults", path, cfg)
+		}
+	}
+
+	// The file only replaces what it sets
+	cfg, err := LoadConfig(writeConfig(t, `{"accountBalance": 50000, "runsDir": "/var/lib/opg"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AccountBalance != 50000 || cfg.RunsDir != "/var/lib/opg" || cfg.LossTolerance != .02 {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestLoadConfigEnvironment(t *testing.T) {
+	t.Setenv("RAPIDAPI_KEY", "from-env")
+	t.Setenv("OPENFIGI_KEY", "figi-env")
+	t.Setenv("TELEGRAM_BOT_TOKEN", "123:env")
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/env")
+
+	cfg, err := LoadConfig(writeConfig(t, `{
+		"apiKey": "from-file",
+		"identifiers": {"openFigiKey": "figi-file"},
+		"alerts": {"notify": {"telegram": {"botToken": "123:file", "chatId": "42"}}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.APIKey != "from-env" || cfg.Identifiers.OpenFIGIKey != "figi-env" {
+		t.Errorf("got keys %q and %q, want the environment's", cfg.APIKey, cfg.Identifiers.OpenFIGIKey)
+	}
+	if cfg.Alerts.Notify.Telegram.BotToken != "123:env" || cfg.Alerts.Notify.SlackWebhook != "https://hooks.slack.com/env" {
+		t.Errorf("got notify %+v %+v", cfg.Alerts.Notify, cfg.Alerts.Notify.Telegram)
+	}
+}
+
+func TestLoadConfigRejects(t *testing.T) {
+	t.Setenv("RAPIDAPI_KEY", "")
+
+	for _, tc := range []struct {
+		name string
+		json string
+		want string
+	}{
+		{"zero balance", `{"accountBalance": 0}`, "accountBalance"},
+		{"negative balance", `{"accountBalance": -5}`, "accountBalance"},
+		{"no tolerance", `{"lossTolerance": 0}`, "lossTolerance"},
+		{"whole balance", `{"lossTolerance": 1}`, "lossTolerance"},
+		{"no profit", `{"profitPercent": 0}`, "profitPercent"},
+		{"timezone", `{"displayTimezone": "Mars/Olympus"}`, "displayTimezone"},
+		{"rounding", `{"rounding": {"mode": "up"}}`, "rounding"},
+		{"encryption", `{"encryption": {"age": ["age1x"], "gpg": ["ABCD"]}}`, "encryption"},
+		{"forex without rate", `{"instruments": {"EURGBP": {"type": "forex"}}}`, "EURGBP"},
+		{"not json", `{"accountBalance": }`, "error decoding config"},
+	} {
+		_, err := LoadConfig(writeConfig(t, tc.json))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error about %s", tc.name, err, tc.want)
+		}
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file: want an error")
+	}
+}
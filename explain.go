@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Explanation is the math behind a selection, added to the plan with
+// -explain to audit it or to learn how the model works. Amounts are in the
+// account currency, prices in the ticker's.
+type Explanation struct {
+	// As in the input, -0.1 for a 10% gap down
+	Gap          float64
+	OpeningPrice float64
+
+	// Where the stock closed before the gap, open / (1 + gap)
+	PreviousClose float64
+
+	// Previous close - open, what the gap is worth per share
+	GapValue float64
+
+	// profitPercent × gap value, how far the target is above the entry and
+	// the stop below it before rounding to the tick. Negative for shorts.
+	TargetDistance float64
+	Tick           float64
+
+	// What a one point move is worth per share, contract or lot
+	PointValue float64
+
+	// |entry - stop| × point value, lost per share when the stop is hit
+	RiskPerShare float64
+
+	// balance × lossTolerance, the most one trade may lose
+	MaxLoss float64
+
+	// How the share count came out, one step per line
+	Sizing []string
+
+	// The filters the stock passed to be selected
+	Filters []string
+}
+
+// explainSizing describes how max loss and risk per share make the count
+func (x *Explanation) explainSizing(err error, unit string) {
+	if x.PointValue != 1 && err == nil {
+		x.Sizing = append(x.Sizing, fmt.Sprintf("a one point move is worth %.6g a %s", x.PointValue, unit))
+	}
+
+	switch {
+	case err != nil:
+		x.Sizing = append(x.Sizing, fmt.Sprintf("not sized: %v", err))
+	case x.RiskPerShare == 0:
+		x.Sizing = append(x.Sizing, "not sized: the stop rounds to the entry, there's no risk to size from")
+	default:
+		n := x.MaxLoss / x.RiskPerShare
+		line := fmt.Sprintf("max loss %.2f / risk %.6g a %s = %.4g, rounded down to %d", x.MaxLoss, x.RiskPerShare, unit, n, int(n+1e-9))
+		if n < 1 {
+			line += fmt.Sprintf(", one %s would risk more than the max loss", unit)
+		}
+		x.Sizing = append(x.Sizing, line)
+	}
+}
+
+// unitsOf names what Shares counts for the instrument
+func unitsOf(in Instrument, ok bool) string {
+	switch {
+	case !ok:
+		return "share"
+	case in.Type == instrumentForex:
+		return "lot"
+	}
+	return "contract"
+}
+
+// round keeps the explanation readable, the math behind it isn't rounded
+func (x *Explanation) round() {
+	for _, v := range []*float64{&x.PreviousClose, &x.GapValue, &x.TargetDistance, &x.RiskPerShare} {
+		*v = math.Round(*v*1e6) / 1e6
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestExplainShares(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+
+	// Gapped up 10% from 98.96, short with the target 7.92 below
+	p, x := e.calculate("MSFT", .1, 108.86)
+	if x.PreviousClose != 98.963636 || x.GapValue != -9.896364 || x.TargetDistance != -7.917091 {
+		t.Errorf("got close %v, gap value %v, distance %v", x.PreviousClose, x.GapValue, x.TargetDistance)
+	}
+	if x.RiskPerShare != 7.92 || x.MaxLoss != 200 || p.Shares != 25 {
+		t.Errorf("got risk %v, max loss %v, %d shares", x.RiskPerShare, x.MaxLoss, p.Shares)
+	}
+
+	want := "max loss 200.00 / risk 7.92 a share = 25.25, rounded down to 25"
+	if len(x.Sizing) != 1 || x.Sizing[0] != want {
+		t.Errorf("got sizing %q, want %q", x.Sizing, want)
+	}
+	if len(x.Filters) != 1 || x.Filters[0] != "gap of 10.00% is at least the 10% minimum" {
+		t.Errorf("got filters %q", x.Filters)
+	}
+
+	// Too expensive for a single share
+	_, x = e.calculate("BRK.A", -.1, 576721.27)
+	want = "max loss 200.00 / risk 51264.1 a share = 0.003901, rounded down to 0, one share would risk more than the max loss"
+	if x.Sizing[0] != want {
+		t.Errorf("got sizing %q, want %q", x.Sizing, want)
+	}
+}
+
+func TestExplainContracts(t *testing.T) {
+	_, x := futuresEngine(5_000_000, 2_000_000).calculate("ESU4", -.1, 5000)
+
+	want := []string{
+		"a one point move is worth 50 a contract",
+		"max loss 100000.00 / risk 22225 a contract = 4.499, rounded down to 4",
+		"capped at 2 by margin: balance 5000000.00 / margin 2000000.00 a contract",
+	}
+	if len(x.Sizing) != len(want) {
+		t.Fatalf("got sizing %q, want %q", x.Sizing, want)
+	}
+	for i := range want {
+		if x.Sizing[i] != want[i] {
+			t.Errorf("step %d: got %q, want %q", i, x.Sizing[i], want[i])
+		}
+	}
+
+	// No CAD rate to size from
+	_, x = NewEngine(DefaultConfig(), nil).calculate("RY.TO", -.1, 100)
+	if len(x.Sizing) != 1 || x.Sizing[0] != "not sized: no rate to convert CAD to USD, add it to rates" {
+		t.Errorf("got sizing %q", x.Sizing)
+	}
+}
+
+func TestRunExplains(t *testing.T) {
+	c, err := loadCassette("testdata/opg.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, explain := range []bool{false, true} {
+		c.Rewind()
+		engine := NewEngine(DefaultConfig(), &http.Client{Transport: &replayingTransport{cassette: c}})
+		engine.explain = explain
+
+		sink := &sliceSink{}
+		if _, err := engine.Run(context.Background(), "opg.csv", sink); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, sel := range sink.selections {
+			if (sel.Explanation != nil) != explain {
+				t.Errorf("%s: got explanation %v with -explain %v", sel.Ticker, sel.Explanation != nil, explain)
+			}
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// runFilesDir is where a run keeps copies of its files, next to its summary.
+// The input and the output are overwritten by the next run, the copies
+// are what export-run packages.
+func runFilesDir(runsDir, id string) string {
+	return filepath.Join(runsDir, id)
+}
+
+// saveRunFiles keeps the input, the config the run used, its outputs and
+// its log. Outputs are copied as written, encrypted ones stay encrypted. With
+// encryption enabled the input, config and log are encrypted the same way,
+// they show the account's positions and balances as much as the plan does.
+func saveRunFiles(dir string, cfg Config, inputPath string, outputs []string, logs []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	if err := keepFile(filepath.Join(dir, "input"+filepath.Ext(inputPath)), input, cfg.Encryption); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg.redacted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := keepFile(filepath.Join(dir, "config.json"), bytes.NewReader(data), cfg.Encryption); err != nil {
+		return err
+	}
+
+	for _, path := range outputs {
+		if err := copyFile(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+			return err
+		}
+	}
+
+	return keepFile(filepath.Join(dir, "run.log"), bytes.NewReader(logs), cfg.Encryption)
+}
+
+// keepFile writes r to path, through the encryption if enabled
+func keepFile(path string, r io.Reader, enc Encryption) error {
+	f, _, err := createOutput(path, enc)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
+
+// redacted is the config without its credentials, safe to hand to someone else
+func (c Config) redacted() Config {
+	// Copies of everything shared with c that gets changed
+	if c.Alerts.Notify.Telegram != nil {
+		telegram := *c.Alerts.Notify.Telegram
+		c.Alerts.Notify.Telegram = &telegram
+	}
+	c.Accounts = append([]Account(nil), c.Accounts...)
+
+	for _, field := range c.secrets() {
+		if *field != "" {
+			*field = "REDACTED"
+		}
+	}
+
+	for i, a := range c.Accounts {
+		if len(a.Credentials) == 0 {
+			continue
+		}
+		creds := map[string]string{}
+		for key := range a.Credentials {
+			creds[key] = "REDACTED"
+		}
+		c.Accounts[i].Credentials = creds
+	}
+
+	return c
+}
+
+// logTee copies the log output to every run in progress. Runs of a batch
+// overlap, each of their logs holds the lines of the others too.
+type logTee struct {
+	mu   sync.Mutex
+	out  io.Writer
+	bufs map[*bytes.Buffer]bool
+}
+
+var runLogs = &logTee{bufs: map[*bytes.Buffer]bool{}}
+
+// capture collects the log output until stop is called
+func (t *logTee) capture() (buf *bytes.Buffer, stop func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.out == nil {
+		t.out = log.Writer()
+		log.SetOutput(t)
+	}
+
+	buf = &bytes.Buffer{}
+	t.bufs[buf] = true
+
+	return buf, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.bufs, buf)
+	}
+}
+
+func (t *logTee) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for buf := range t.bufs {
+		buf.Write(p)
+	}
+	return t.out.Write(p)
+}
+
+// findRun returns the newest run whose ID starts with id, or the newest run
+// at all for "latest"
+func findRun(summaries []*Summary, id string) (*Summary, error) {
+	for _, s := range summaries {
+		if id == "latest" || strings.HasPrefix(s.ID, id) {
+			return s, nil
+		}
+	}
+
+	if id == "latest" {
+		return nil, errors.New("no runs recorded yet")
+	}
+	return nil, fmt.Errorf("no run %s", id)
+}
+
+// exportRun writes the run's summary and kept files to a gzipped tar, all
+// under a directory named after the run
+func exportRun(w io.Writer, runsDir string, s *Summary) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	summary, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{Name: s.ID + "/summary.json", Mode: 0o644, Size: int64(len(summary)), ModTime: s.FinishedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(summary); err != nil {
+		return err
+	}
+
+	dir := runFilesDir(runsDir, s.ID)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		// Runs from before files were kept
+		log.Printf("Warning: no files kept for run %s, only the summary is exported", s.ID)
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := addToArchive(tw, filepath.Join(dir, entry.Name()), s.ID+"/"+entry.Name()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addToArchive(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Packages one run into a single archive to share it
+func exportRunCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export-run", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON config file")
+	account := fs.String("account", "", "export from the journal of this account")
+	id := fs.String("run", "latest", "ID of the run, or the start of it")
+	outPath := fs.String("out", "", "archive to write, opg-run-<id>.tar.gz by default")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if *account != "" {
+		if cfg, err = cfg.forAccount(*account); err != nil {
+			return err
+		}
+	}
+
+	summaries, err := loadSummaries(cfg.RunsDir)
+	if err != nil {
+		return err
+	}
+
+	s, err := findRun(summaries, *id)
+	if err != nil {
+		return err
+	}
+
+	path := *outPath
+	if path == "" {
+		path = "opg-run-" + s.ID + ".tar.gz"
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := exportRun(f, cfg.RunsDir, s); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("error exporting run %s: %w", s.ID, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Exported run %s to %s", s.ID, path)
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportRun(t *testing.T) {
+	c, err := loadCassette("testdata/opg.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.RunsDir = filepath.Join(dir, "runs")
+	cfg.APIKey = "rapid-key"
+	cfg.Accounts = []Account{{Name: "main", AccountBalance: 1000, Credentials: map[string]string{"password": "hunter2"}}}
+
+	engine := NewEngine(cfg, &http.Client{Transport: &replayingTransport{cassette: c}})
+	summary, err := runPlan(context.Background(), engine, &options{}, "opg.csv", filepath.Join(dir, "opg.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The config given to the run keeps its credentials
+	if cfg.APIKey != "rapid-key" || cfg.Accounts[0].Credentials["password"] != "hunter2" {
+		t.Fatal("redacting changed the config in use")
+	}
+
+	var buf bytes.Buffer
+	if err := exportRun(&buf, cfg.RunsDir, summary); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readArchive(t, &buf)
+
+	for _, name := range []string{"summary.json", "input.csv", "config.json", "opg.json", "run.log"} {
+		if _, ok := files[summary.ID+"/"+name]; !ok {
+			t.Errorf("%s missing from the archive, got %v", name, keys(files))
+		}
+	}
+
+	config := files[summary.ID+"/config.json"]
+	if strings.Contains(config, "rapid-key") || strings.Contains(config, "hunter2") || !strings.Contains(config, "REDACTED") {
+		t.Errorf("credentials not redacted:\n%s", config)
+	}
+	if !strings.Contains(files[summary.ID+"/run.log"], "Finished writing output") {
+		t.Errorf("run log missing the run's lines:\n%s", files[summary.ID+"/run.log"])
+	}
+	if !strings.Contains(files[summary.ID+"/opg.json"], "MSFT") {
+		t.Error("plan missing its selections")
+	}
+}
+
+func TestFindRun(t *testing.T) {
+	// Newest first, as loadSummaries returns them
+	summaries := []*Summary{{ID: "20240116-092000-aaaaaa"}, {ID: "20240115-092000-bbbbbb"}}
+
+	for id, want := range map[string]string{"latest": "20240116-092000-aaaaaa", "20240115": "20240115-092000-bbbbbb"} {
+		s, err := findRun(summaries, id)
+		if err != nil || s.ID != want {
+			t.Errorf("%s: got %v %v, want %s", id, s, err, want)
+		}
+	}
+
+	if _, err := findRun(summaries, "2023"); err == nil {
+		t.Error("want an error for an unknown run")
+	}
+	if _, err := findRun(nil, "latest"); err == nil {
+		t.Error("want an error without runs")
+	}
+}
+
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = string(data)
+	}
+}
+
+func keys(m map[string]string) []string {
+	var k []string
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
+
+func TestRunFilesEncrypted(t *testing.T) {
+	fakeAge(t)
+
+	c, err := loadCassette("testdata/opg.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.RunsDir = filepath.Join(dir, "runs")
+	cfg.Encryption = Encryption{Age: []string{"age1alice"}}
+
+	engine := NewEngine(cfg, &http.Client{Transport: &replayingTransport{cassette: c}})
+	summary, err := runPlan(context.Background(), engine, &options{}, "opg.csv", filepath.Join(dir, "opg.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runDir := runFilesDir(cfg.RunsDir, summary.ID)
+	for _, name := range []string{"input.csv", "config.json", "opg.json", "opg.meta.json", "run.log"} {
+		if _, err := os.Stat(filepath.Join(runDir, name)); !os.IsNotExist(err) {
+			t.Errorf("plain %s kept with an encrypted run", name)
+		}
+
+		// The fake age passes its recipients on first
+		data, err := os.ReadFile(filepath.Join(runDir, name+".age"))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !strings.HasPrefix(string(data), "-r age1alice\n") {
+			t.Errorf("%s.age didn't go through the encrypter", name)
+		}
+	}
+}
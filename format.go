@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// Formatting controls how prices and amounts are printed in reports. Output
+// files keep plain numbers.
+type Formatting struct {
+	// Decimals of prices, as many as the tick size needs when not set
+	// (2 for $0.01, 4 for sub-penny)
+	PriceDecimals *int `json:"priceDecimals,omitempty"`
+
+	// Decimals of amounts of money, 2 when not set
+	MoneyDecimals *int `json:"moneyDecimals,omitempty"`
+
+	// Currency of each ticker's prices by ISO code, e.g. {"SAP": "EUR"}. The
+	// account currency is assumed otherwise.
+	Currencies map[string]string `json:"currencies,omitempty"`
+
+	// Symbols printed for currencies, on top of the built-in ones
+	Symbols map[string]string `json:"symbols,omitempty"`
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CAD": "C$",
+	"AUD": "A$",
+	"CHF": "CHF",
+}
+
+func (f Formatting) symbol(currency string) string {
+	if s, ok := f.Symbols[currency]; ok {
+		return s
+	}
+	if s, ok := currencySymbols[currency]; ok {
+		return s
+	}
+	return currency
+}
+
+// tick is the price increment of a ticker: the instrument's tick or pip, or
+// the stock tick size
+func (e *Engine) tick(ticker string, price float64) float64 {
+	if in, ok := e.instrument(ticker); ok {
+		if in.Type == instrumentForex {
+			return in.pip(ticker)
+		}
+		return in.TickSize
+	}
+	return e.cfg.Rounding.Tick(ticker, price)
+}
+
+// priceCurrency is the currency a ticker is quoted in
+func (e *Engine) priceCurrency(ticker string) string {
+	if c, ok := e.cfg.Formatting.Currencies[ticker]; ok {
+		return c
+	}
+	if in, ok := e.instrument(ticker); ok && in.Type == instrumentForex {
+		_, quote, _ := forexPair(ticker)
+		return quote
+	}
+	if _, v, ok := e.cfg.venue(ticker); ok {
+		return v.Currency
+	}
+	return e.cfg.accountCurrency()
+}
+
+// formatPrice prints a price in the ticker's currency with the decimals its
+// tick size needs
+func (e *Engine) formatPrice(l *Locale, ticker string, price float64) string {
+	decimals := decimalsFor(e.tick(ticker, price))
+	if d := e.cfg.Formatting.PriceDecimals; d != nil {
+		decimals = *d
+	}
+
+	return l.Money(price, decimals, e.cfg.Formatting.symbol(e.priceCurrency(ticker)))
+}
+
+// formatMoney prints an amount in the account currency
+func (e *Engine) formatMoney(l *Locale, v float64) string {
+	decimals := 2
+	if d := e.cfg.Formatting.MoneyDecimals; d != nil {
+		decimals = *d
+	}
+
+	return l.Money(v, decimals, e.cfg.Formatting.symbol(e.cfg.accountCurrency()))
+}
+
+// decimalsFor returns how many decimals it takes to write step: 2 for 0.25, 4 for 0.0001
+func decimalsFor(step float64) int {
+	for d := 0; d < 8; d++ {
+		v := step * math.Pow10(d)
+		if math.Abs(v-math.Round(v)) < 1e-9 {
+			return d
+		}
+	}
+	return 8
+}
+
+// Money prints v with a currency symbol where the locale puts it: $1,234.50 or 1.234,50 €
+func (l *Locale) Money(v float64, decimals int, symbol string) string {
+	n := l.Number(v, decimals)
+
+	if l.CurrencyAfter {
+		return n + " " + symbol
+	}
+	if rest, ok := strings.CutPrefix(n, "-"); ok {
+		return "-" + symbol + rest
+	}
+	return symbol + n
+}
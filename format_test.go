@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestFormatPrice(t *testing.T) {
+	two := 2
+	cfg := DefaultConfig()
+	cfg.Rates = map[string]float64{"JPY": .0065}
+	cfg.Instruments = map[string]Instrument{
+		"ES":     {Type: instrumentFuture, TickSize: .25, Multiplier: 50},
+		"USDJPY": {Type: instrumentForex},
+		"EURUSD": {Type: instrumentForex},
+	}
+	cfg.Formatting.Currencies = map[string]string{"SAP": "EUR", "SHEL": "GBP"}
+	e := NewEngine(cfg, nil)
+
+	en, _ := LoadLocale("en")
+	de, _ := LoadLocale("de")
+
+	tests := []struct {
+		l      *Locale
+		ticker string
+		price  float64
+		want   string
+	}{
+		{en, "MSFT", 1108.86, "$1,108.86"},
+		{en, "PENNY", .1235, "$0.1235"},
+		{en, "ES", 5001.25, "$5,001.25"},
+		{en, "EURUSD", 1.0912, "$1.0912"},
+		{en, "USDJPY", 158.12, "¥158.12"},
+		{en, "SHEL", 27.5, "£27.50"},
+		{de, "SAP", 1234.5, "1.234,50 €"},
+		{de, "PENNY", .1235, "0,1235 $"},
+	}
+
+	for _, tt := range tests {
+		if got := e.formatPrice(tt.l, tt.ticker, tt.price); got != tt.want {
+			t.Errorf("%s formatPrice(%s, %v) = %q, want %q", tt.l.Language, tt.ticker, tt.price, got, tt.want)
+		}
+	}
+
+	// Fixed precision wins over the tick size
+	cfg.Formatting.PriceDecimals = &two
+	if got := NewEngine(cfg, nil).formatPrice(en, "PENNY", .1235); got != "$0.12" {
+		t.Errorf("fixed decimals: got %q", got)
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	zero := 0
+	cfg := DefaultConfig()
+	cfg.AccountCurrency = "EUR"
+	cfg.Formatting.Symbols = map[string]string{"EUR": "EUR"}
+	e := NewEngine(cfg, nil)
+
+	en, _ := LoadLocale("en")
+	if got := e.formatMoney(en, -1234.5); got != "-EUR1,234.50" {
+		t.Errorf("got %q", got)
+	}
+
+	cfg.Formatting.MoneyDecimals = &zero
+	es, _ := LoadLocale("es")
+	if got := NewEngine(cfg, nil).formatMoney(es, 1234.4); got != "1.234 EUR" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDecimalsFor(t *testing.T) {
+	for step, want := range map[float64]int{1: 0, .25: 2, .01: 2, .05: 2, .0001: 4, .5: 1, .00001: 5} {
+		if got := decimalsFor(step); got != want {
+			t.Errorf("decimalsFor(%v) = %d, want %d", step, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Hosts the providers are reached at, checked by /readyz
+var providerHosts = []string{"seeking-alpha.p.rapidapi.com:443"}
+
+// health tracks what the daemon's probes report
+type health struct {
+	mu sync.Mutex
+
+	configLoaded bool
+	lastSuccess  time.Time
+	lastRunID    string
+	lastError    string
+	lastErrorAt  time.Time
+
+	// Provider reachability is cached so frequent probes don't hammer DNS
+	probe       func(ctx context.Context) error
+	probedAt    time.Time
+	probeErr    error
+	probeMaxAge time.Duration
+}
+
+func newHealth() *health {
+	return &health{probe: dialProviders, probeMaxAge: 30 * time.Second}
+}
+
+func (h *health) setConfigLoaded() {
+	h.mu.Lock()
+	h.configLoaded = true
+	h.mu.Unlock()
+}
+
+func (h *health) recordRun(summary *Summary, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.lastError = err.Error()
+		h.lastErrorAt = time.Now()
+		return
+	}
+
+	h.lastSuccess = time.Now()
+	if summary != nil {
+		h.lastRunID = summary.ID
+	}
+}
+
+// providers returns the cached reachability, probing again when it's stale
+func (h *health) providers(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if time.Since(h.probedAt) > h.probeMaxAge {
+		h.probeErr = h.probe(ctx)
+		h.probedAt = time.Now()
+	}
+
+	return h.probeErr
+}
+
+func dialProviders(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var d net.Dialer
+	for _, host := range providerHosts {
+		conn, err := d.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	}
+
+	return nil
+}
+
+type readiness struct {
+	Ready              bool       `json:"ready"`
+	ConfigLoaded       bool       `json:"configLoaded"`
+	ProvidersReachable bool       `json:"providersReachable"`
+	ProviderError      string     `json:"providerError,omitempty"`
+	LastSuccessfulRun  *time.Time `json:"lastSuccessfulRun,omitempty"`
+	LastRunID          string     `json:"lastRunId,omitempty"`
+	LastError          string     `json:"lastError,omitempty"`
+	LastErrorAt        *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+func (h *health) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Liveness: the process is up and serving
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+
+	// Readiness: config loaded and providers reachable. The last run is
+	// reported for uptime monitors but doesn't make the daemon unready.
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		providerErr := h.providers(r.Context())
+
+		h.mu.Lock()
+		res := readiness{
+			ConfigLoaded:       h.configLoaded,
+			ProvidersReachable: providerErr == nil,
+			LastRunID:          h.lastRunID,
+			LastError:          h.lastError,
+		}
+		if providerErr != nil {
+			res.ProviderError = providerErr.Error()
+		}
+		if !h.lastSuccess.IsZero() {
+			t := h.lastSuccess
+			res.LastSuccessfulRun = &t
+		}
+		if !h.lastErrorAt.IsZero() {
+			t := h.lastErrorAt
+			res.LastErrorAt = &t
+		}
+		h.mu.Unlock()
+
+		res.Ready = res.ConfigLoaded && res.ProvidersReachable
+
+		w.Header().Set("Content-Type", "application/json")
+		if !res.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(res)
+	})
+
+	return mux
+}
+
+// serve runs the HTTP server until ctx is done
+func serve(ctx context.Context, addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	probeErr := errors.New("dial tcp: i/o timeout")
+	h := newHealth()
+	h.probe = func(context.Context) error { return probeErr }
+	h.probeMaxAge = 0
+
+	get := func(path string) (int, readiness) {
+		rec := httptest.NewRecorder()
+		h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var res readiness
+		if path == "/readyz" {
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, res
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("/healthz: got %d", code)
+	}
+
+	h.setConfigLoaded()
+	if code, res := get("/readyz"); code != http.StatusServiceUnavailable || res.ProvidersReachable || res.ProviderError == "" {
+		t.Fatalf("/readyz with unreachable providers: got %d %+v", code, res)
+	}
+
+	probeErr = nil
+	h.recordRun(&Summary{ID: "20240311-092000-abcdef"}, nil)
+	h.recordRun(nil, errors.New("refusing to run"))
+
+	code, res := get("/readyz")
+	if code != http.StatusOK || !res.Ready {
+		t.Fatalf("/readyz: got %d %+v", code, res)
+	}
+	if res.LastSuccessfulRun == nil || res.LastRunID != "20240311-092000-abcdef" || res.LastError != "refusing to run" {
+		t.Fatalf("/readyz run details: got %+v", res)
+	}
+}
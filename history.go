@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// loadSummaries reads the run records in dir, newest first. Other JSON files
+// in the directory, like the scheduler state, are skipped.
+func loadSummaries(dir string) ([]*Summary, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []*Summary
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var s Summary
+		if err := json.Unmarshal(data, &s); err != nil || s.ID == "" {
+			continue
+		}
+		summaries = append(summaries, &s)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+
+	return summaries, nil
+}
+
+// hasTags reports whether the run was given all of tags
+func (s *Summary) hasTags(tags []string) bool {
+	for _, t := range tags {
+		if !slices.Contains(s.Tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Lists past runs, optionally only those with the given tags
+func historyCommand(ctx context.Context, args []string) error {
+	var tags listFlag
+
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON config file")
+	limit := fs.Int("limit", 20, "number of runs to show, 0 for all")
+	account := fs.String("account", "", "show the journal of this account")
+	fs.Var(&tags, "tag", "only runs with this tag, repeatable")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if *account != "" {
+		if cfg, err = cfg.forAccount(*account); err != nil {
+			return err
+		}
+	}
+
+	summaries, err := loadSummaries(cfg.RunsDir)
+	if err != nil {
+		return err
+	}
+
+	shown := 0
+	for _, s := range summaries {
+		if !s.hasTags(tags) {
+			continue
+		}
+		if *limit > 0 && shown == *limit {
+			break
+		}
+		shown++
+
+		fmt.Printf("%s  %3d selected %2d failed  %s", s.ID, s.Selected, len(s.Failures), s.Input)
+		if len(s.Tags) > 0 {
+			fmt.Printf("  [%s]", strings.Join(s.Tags, ", "))
+		}
+		if s.Note != "" {
+			fmt.Printf("  %q", s.Note)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSummaries(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 7, 9, 9, 20, 0, 0, time.UTC)
+
+	for i, tags := range [][]string{{"fomc-day"}, nil, {"fomc-day", "half-size"}} {
+		s := &Summary{ID: string(rune('a' + i)), StartedAt: start.AddDate(0, 0, i), Tags: tags}
+		if _, err := s.Save(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Scheduler state lives in the same directory
+	os.WriteFile(filepath.Join(dir, "scheduler.json"), []byte(`{"premarket": "2024-07-09T09:20:00Z"}`), 0o644)
+
+	summaries, err := loadSummaries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 3 || summaries[0].ID != "c" || summaries[2].ID != "a" {
+		t.Fatalf("want runs c, b, a, got %d runs", len(summaries))
+	}
+
+	var tagged []string
+	for _, s := range summaries {
+		if s.hasTags([]string{"fomc-day"}) {
+			tagged = append(tagged, s.ID)
+		}
+	}
+	if len(tagged) != 2 || tagged[0] != "c" || tagged[1] != "a" {
+		t.Errorf("fomc-day runs: got %v, want [c a]", tagged)
+	}
+
+	if !summaries[1].hasTags(nil) || summaries[0].hasTags([]string{"fomc-day", "other"}) {
+		t.Error("all given tags have to match")
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const openFIGIURL = "https://api.openfigi.com/v3/mapping"
+
+// Identifiers other than the ticker, for systems that don't use US tickers
+type Identifiers struct {
+	ISIN  string `json:",omitempty"`
+	CUSIP string `json:",omitempty"`
+	FIGI  string `json:",omitempty"`
+}
+
+// IdentifierConfig says where tickers and ISIN/CUSIP/FIGI are mapped from
+type IdentifierConfig struct {
+	// CSV with a header naming the columns: ticker, isin, cusip, figi
+	MapFile string `json:"mapFile,omitempty"`
+
+	// Ask OpenFIGI for what the map file doesn't know
+	OpenFIGI bool `json:"openFigi,omitempty"`
+
+	// Raises the OpenFIGI rate limit, OPENFIGI_KEY in the environment wins
+	OpenFIGIKey string `json:"openFigiKey,omitempty"`
+}
+
+// Kinds of identifier an input row can be keyed by
+const (
+	idTicker = "ticker"
+	idISIN   = "ISIN"
+	idCUSIP  = "CUSIP"
+	idFIGI   = "FIGI"
+)
+
+// identifierKind tells ISIN, CUSIP and FIGI apart from tickers by their
+// length and check digit, so a ticker is never mistaken for one
+func identifierKind(id string) string {
+	switch {
+	case len(id) == 12 && strings.HasPrefix(id, "BBG") && validFIGI(id):
+		return idFIGI
+	case len(id) == 12 && validISIN(id):
+		return idISIN
+	case len(id) == 9 && validCUSIP(id):
+		return idCUSIP
+	}
+	return idTicker
+}
+
+// validISIN checks the country code and the Luhn check digit over the
+// digits the letters expand to (A=10 ... Z=35)
+func validISIN(id string) bool {
+	if len(id) != 12 || !isUpper(id[0]) || !isUpper(id[1]) || !isDigit(id[11]) {
+		return false
+	}
+
+	var digits []byte
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case isDigit(c):
+			digits = append(digits, c-'0')
+		case isUpper(c):
+			v := c - 'A' + 10
+			digits = append(digits, v/10, v%10)
+		default:
+			return false
+		}
+	}
+
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i])
+		if i%2 == 1 {
+			d *= 2
+		}
+		sum += d/10 + d%10
+	}
+
+	return sum%10 == 0
+}
+
+// validCUSIP checks the "double add double" check digit of a CUSIP
+func validCUSIP(id string) bool {
+	return len(id) == 9 && doubleAddDouble(id[:8]) == int(id[8])-'0'
+}
+
+// FIGIs use the CUSIP check digit over eleven characters
+func validFIGI(id string) bool {
+	return len(id) == 12 && doubleAddDouble(id[:11]) == int(id[11])-'0'
+}
+
+func doubleAddDouble(s string) int {
+	sum := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		var v int
+		switch {
+		case isDigit(c):
+			v = int(c - '0')
+		case isUpper(c):
+			v = int(c-'A') + 10
+		case c == '*':
+			v = 36
+		case c == '@':
+			v = 37
+		case c == '#':
+			v = 38
+		default:
+			return -1
+		}
+
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v/10 + v%10
+	}
+
+	return (10 - sum%10) % 10
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+
+// idMapper resolves identifiers to tickers and back, from the map file first
+// and OpenFIGI second. Answers from OpenFIGI are kept for the engine's lifetime.
+type idMapper struct {
+	cfg     IdentifierConfig
+	client  *http.Client
+	figiURL string
+
+	load    sync.Once
+	loadErr error
+
+	mu       sync.Mutex
+	byTicker map[string]Identifiers
+	tickers  map[string]string
+}
+
+func newIDMapper(cfg IdentifierConfig, client *http.Client) *idMapper {
+	return &idMapper{
+		cfg:      cfg,
+		client:   client,
+		figiURL:  openFIGIURL,
+		byTicker: map[string]Identifiers{},
+		tickers:  map[string]string{},
+	}
+}
+
+func (m *idMapper) add(ticker string, ids Identifiers) {
+	m.byTicker[ticker] = ids
+	for _, id := range []string{ids.ISIN, ids.CUSIP, ids.FIGI} {
+		if id != "" {
+			m.tickers[id] = ticker
+		}
+	}
+}
+
+func (m *idMapper) loadMapFile() error {
+	if m.cfg.MapFile == "" {
+		return nil
+	}
+
+	f, err := os.Open(m.cfg.MapFile)
+	if err != nil {
+		return fmt.Errorf("error reading identifier map: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("error reading identifier map %s: %w", m.cfg.MapFile, err)
+	}
+
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["ticker"]; !ok {
+		return fmt.Errorf("identifier map %s has no ticker column", m.cfg.MapFile)
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading identifier map %s: %w", m.cfg.MapFile, err)
+		}
+
+		ids := Identifiers{ISIN: field(row, "isin"), CUSIP: field(row, "cusip"), FIGI: field(row, "figi")}
+		if ids.ISIN != "" && !validISIN(ids.ISIN) {
+			return fmt.Errorf("identifier map %s: invalid ISIN %s", m.cfg.MapFile, ids.ISIN)
+		}
+		if ids.CUSIP != "" && !validCUSIP(ids.CUSIP) {
+			return fmt.Errorf("identifier map %s: invalid CUSIP %s", m.cfg.MapFile, ids.CUSIP)
+		}
+
+		m.add(field(row, "ticker"), ids)
+	}
+}
+
+// Resolve turns an input key into a ticker and the identifiers known for it.
+// Tickers pass through, only their identifiers are looked up, so the ticker
+// may come back together with an error about those.
+func (m *idMapper) Resolve(ctx context.Context, key string) (string, Identifiers, error) {
+	if m.load.Do(func() { m.loadErr = m.loadMapFile() }); m.loadErr != nil {
+		return "", Identifiers{}, m.loadErr
+	}
+
+	kind := identifierKind(key)
+
+	m.mu.Lock()
+	ticker, ok := m.tickers[key]
+	if kind == idTicker {
+		ticker, ok = key, true
+	}
+	ids, known := m.byTicker[ticker]
+	m.mu.Unlock()
+
+	if known || (kind == idTicker && !m.cfg.OpenFIGI) {
+		return ticker, ids, nil
+	}
+	if !m.cfg.OpenFIGI {
+		return "", Identifiers{}, fmt.Errorf("no ticker for %s %s in the identifier map", kind, key)
+	}
+
+	figi, figiTicker, err := m.openFIGI(ctx, kind, key)
+	if err != nil {
+		// A ticker is still usable without its identifiers
+		if ok {
+			return ticker, ids, err
+		}
+		return "", Identifiers{}, err
+	}
+	if !ok {
+		ticker = figiTicker
+	}
+
+	// Keep what the input told us, OpenFIGI only knows the FIGI
+	switch kind {
+	case idISIN:
+		ids.ISIN = key
+	case idCUSIP:
+		ids.CUSIP = key
+	}
+	ids.FIGI = figi
+
+	m.mu.Lock()
+	m.add(ticker, ids)
+	m.mu.Unlock()
+
+	return ticker, ids, nil
+}
+
+type figiJob struct {
+	IDType   string `json:"idType"`
+	IDValue  string `json:"idValue"`
+	ExchCode string `json:"exchCode,omitempty"`
+}
+
+type figiResult struct {
+	Data []struct {
+		FIGI   string `json:"figi"`
+		Ticker string `json:"ticker"`
+	} `json:"data"`
+	Warning string `json:"warning"`
+	Error   string `json:"error"`
+}
+
+// openFIGI maps one identifier to the FIGI and ticker of its US listing
+func (m *idMapper) openFIGI(ctx context.Context, kind, id string) (string, string, error) {
+	idType := map[string]string{
+		idTicker: "TICKER",
+		idISIN:   "ID_ISIN",
+		idCUSIP:  "ID_CUSIP",
+		idFIGI:   "ID_BB_GLOBAL",
+	}[kind]
+
+	// OpenFIGI writes share classes with a slash, BRK/A
+	if kind == idTicker {
+		id = strings.ReplaceAll(id, ".", "/")
+	}
+
+	body, err := json.Marshal([]figiJob{{IDType: idType, IDValue: id, ExchCode: "US"}})
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.figiURL, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.cfg.OpenFIGIKey != "" {
+		req.Header.Set("X-OPENFIGI-APIKEY", m.cfg.OpenFIGIKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("OpenFIGI answered %s", resp.Status)
+	}
+
+	var results []figiResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return "", "", fmt.Errorf("error decoding OpenFIGI response: %w", err)
+	}
+
+	switch {
+	case len(results) != 1:
+		return "", "", errors.New("OpenFIGI answered without a result")
+	case results[0].Error != "":
+		return "", "", fmt.Errorf("OpenFIGI: %s", results[0].Error)
+	case len(results[0].Data) == 0:
+		return "", "", fmt.Errorf("OpenFIGI has no US listing for %s %s", kind, id)
+	}
+
+	d := results[0].Data[0]
+	return d.FIGI, strings.ReplaceAll(d.Ticker, "/", "."), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIdentifierKind(t *testing.T) {
+	tests := map[string]string{
+		"US0378331005": idISIN,
+		"DE0007164600": idISIN,
+		"US0378331006": idTicker, // wrong check digit
+		"037833100":    idCUSIP,
+		"594918104":    idCUSIP,
+		"594918105":    idTicker,
+		"BBG000B9XRY4": idFIGI,
+		"BBG000B9XRY5": idTicker,
+		"AAPL":         idTicker,
+		"BRK.A":        idTicker,
+	}
+
+	for id, want := range tests {
+		if got := identifierKind(id); got != want {
+			t.Errorf("identifierKind(%s) = %s, want %s", id, got, want)
+		}
+	}
+}
+
+func TestIDMapperMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.csv")
+	os.WriteFile(path, []byte("isin,ticker,figi\nUS0378331005,AAPL,BBG000B9XRY4\n"), 0o644)
+
+	m := newIDMapper(IdentifierConfig{MapFile: path}, nil)
+
+	ticker, ids, err := m.Resolve(context.Background(), "US0378331005")
+	if err != nil || ticker != "AAPL" || ids.FIGI != "BBG000B9XRY4" {
+		t.Errorf("ISIN: got %s %+v %v", ticker, ids, err)
+	}
+
+	ticker, ids, err = m.Resolve(context.Background(), "AAPL")
+	if err != nil || ticker != "AAPL" || ids.ISIN != "US0378331005" {
+		t.Errorf("ticker: got %s %+v %v", ticker, ids, err)
+	}
+
+	// Unknown tickers pass through, unknown ISINs can't be traded
+	if ticker, _, err := m.Resolve(context.Background(), "MSFT"); ticker != "MSFT" || err != nil {
+		t.Errorf("unknown ticker: got %s %v", ticker, err)
+	}
+	if _, _, err := m.Resolve(context.Background(), "DE0007164600"); err == nil {
+		t.Error("want error for an ISIN that isn't in the map")
+	}
+}
+
+func TestIDMapperRejectsBadMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.csv")
+	os.WriteFile(path, []byte("ticker,isin\nAAPL,US0378331006\n"), 0o644)
+
+	m := newIDMapper(IdentifierConfig{MapFile: path}, nil)
+	if _, _, err := m.Resolve(context.Background(), "AAPL"); err == nil || !strings.Contains(err.Error(), "invalid ISIN") {
+		t.Errorf("got %v, want invalid ISIN", err)
+	}
+}
+
+func TestIDMapperOpenFIGI(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var jobs []figiJob
+		json.NewDecoder(r.Body).Decode(&jobs)
+		if len(jobs) != 1 || jobs[0].ExchCode != "US" || r.Header.Get("X-OPENFIGI-APIKEY") != "k" {
+			t.Errorf("unexpected request %+v", jobs)
+		}
+
+		switch jobs[0].IDValue {
+		case "US0846707026":
+			w.Write([]byte(`[{"data":[{"figi":"BBG000DWG505","ticker":"BRK/B"}]}]`))
+		default:
+			w.Write([]byte(`[{"warning":"No identifier found."}]`))
+		}
+	}))
+	defer srv.Close()
+
+	m := newIDMapper(IdentifierConfig{OpenFIGI: true, OpenFIGIKey: "k"}, srv.Client())
+	m.figiURL = srv.URL
+
+	ticker, ids, err := m.Resolve(context.Background(), "US0846707026")
+	if err != nil || ticker != "BRK.B" || ids.ISIN != "US0846707026" || ids.FIGI != "BBG000DWG505" {
+		t.Errorf("got %s %+v %v", ticker, ids, err)
+	}
+
+	// Answered from memory the second time, also when asked by ticker
+	m.Resolve(context.Background(), "US0846707026")
+	m.Resolve(context.Background(), "BRK.B")
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+
+	// A ticker OpenFIGI doesn't know is still a ticker
+	ticker, _, err = m.Resolve(context.Background(), "ZZZZ")
+	if ticker != "ZZZZ" || err == nil {
+		t.Errorf("got %s %v, want the ticker and an error", ticker, err)
+	}
+}
+
+func TestIdentifierRowsSizedAsTheirTicker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.csv")
+	os.WriteFile(path, []byte("isin,ticker\nCA7800871021,RY.TO\n"), 0o644)
+
+	cfg := DefaultConfig()
+	cfg.Identifiers.MapFile = path
+	cfg.Rates = map[string]float64{"CAD": .5}
+	e := NewEngine(cfg, &http.Client{Transport: failingTransport{}})
+
+	byTicker := e.enrich(context.Background(), Stock{Ticker: "RY.TO", Gap: -.1, OpeningPrice: 100})
+	byISIN := e.enrich(context.Background(), Stock{Ticker: "CA7800871021", Gap: -.1, OpeningPrice: 100})
+
+	// Sized in CAD at half a dollar, 22 shares as a US listing
+	if byISIN.Ticker != "RY.TO" || byISIN.ISIN != "CA7800871021" || byISIN.Shares != byTicker.Shares || byISIN.Shares != 44 {
+		t.Errorf("got %s with %d shares, want RY.TO with %d", byISIN.Ticker, byISIN.Shares, byTicker.Shares)
+	}
+	if byISIN.Exchange != "Toronto Stock Exchange" || byISIN.Currency != "CAD" {
+		t.Errorf("got %s in %s", byISIN.Exchange, byISIN.Currency)
+	}
+}
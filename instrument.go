@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	instrumentFuture = "future"
+	instrumentForex  = "forex"
+)
+
+// Units of the base currency in a lot
+var lotUnits = map[string]float64{
+	"standard": 100_000,
+	"mini":     10_000,
+	"micro":    1_000,
+}
+
+// Instrument describes how a symbol that isn't a plain stock is priced. A
+// position's size is in contracts or lots then, and its risk is the stop
+// distance times the contract's point value instead of times shares.
+type Instrument struct {
+	Type string `json:"type"`
+
+	// Minimum price move, and either the value of a one point move per
+	// contract or the value of one tick (ES: 0.25 ticks, 50 a point, 12.50 a tick)
+	TickSize   float64 `json:"tickSize,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+	TickValue  float64 `json:"tickValue,omitempty"`
+
+	// Initial margin per contract, caps the contracts at what the balance can hold
+	Margin float64 `json:"margin,omitempty"`
+
+	// Forex: pip size, 0.0001 or 0.01 for yen pairs by default, and the lot
+	// the position is counted in: standard, mini or micro (default)
+	PipSize float64 `json:"pipSize,omitempty"`
+	Lot     string  `json:"lot,omitempty"`
+}
+
+func (in Instrument) Validate(symbol string) error {
+	switch in.Type {
+	case instrumentFuture:
+	case instrumentForex:
+		if _, _, ok := forexPair(symbol); !ok {
+			return fmt.Errorf("instruments.%s: forex symbols are currency pairs like EURUSD", symbol)
+		}
+		if in.PipSize < 0 {
+			return fmt.Errorf("instruments.%s.pipSize must be positive, got %v", symbol, in.PipSize)
+		}
+		if _, ok := lotUnits[in.lot()]; !ok {
+			return fmt.Errorf("instruments.%s.lot must be standard, mini or micro, got %q", symbol, in.Lot)
+		}
+		return nil
+	default:
+		return fmt.Errorf("instruments.%s.type must be %q or %q, got %q", symbol, instrumentFuture, instrumentForex, in.Type)
+	}
+
+	if in.TickSize <= 0 {
+		return fmt.Errorf("instruments.%s.tickSize must be positive, got %v", symbol, in.TickSize)
+	}
+
+	if (in.Multiplier > 0) == (in.TickValue > 0) {
+		return fmt.Errorf("instruments.%s needs either a multiplier or a tickValue", symbol)
+	}
+
+	if in.Margin < 0 {
+		return fmt.Errorf("instruments.%s.margin must not be negative, got %v", symbol, in.Margin)
+	}
+
+	return nil
+}
+
+// PointValue is what a one point move is worth per contract
+func (in Instrument) PointValue() float64 {
+	if in.Multiplier > 0 {
+		return in.Multiplier
+	}
+	return in.TickValue / in.TickSize
+}
+
+func (in Instrument) lot() string {
+	if in.Lot == "" {
+		return "micro"
+	}
+	return in.Lot
+}
+
+// pip returns the pip size of a pair, yen pairs are quoted to two decimals
+func (in Instrument) pip(symbol string) float64 {
+	if in.PipSize > 0 {
+		return in.PipSize
+	}
+	if _, quote, _ := forexPair(symbol); quote == "JPY" {
+		return .01
+	}
+	return .0001
+}
+
+// forexPair splits EURUSD, EUR/USD or EURUSD=X into its currencies
+func forexPair(symbol string) (base, quote string, ok bool) {
+	s := strings.ReplaceAll(strings.TrimSuffix(symbol, "=X"), "/", "")
+	if len(s) != 6 || strings.ToUpper(s) != s {
+		return "", "", false
+	}
+	return s[:3], s[3:], true
+}
+
+// quoteRate is what one unit of the pair's quote currency is worth in the
+// account currency. Pairs quoted in it need no rate, pairs based on it are
+// converted with their own price, anything else needs a rate in the config.
+func (c Config) quoteRate(symbol string, price float64) (float64, error) {
+	base, quote, _ := forexPair(symbol)
+
+	switch {
+	case quote == c.accountCurrency():
+		return 1, nil
+	case base == c.accountCurrency():
+		return 1 / price, nil
+	}
+
+	return c.currencyRate(quote)
+}
+
+func (c Config) accountCurrency() string {
+	if c.AccountCurrency == "" {
+		return "USD"
+	}
+	return c.AccountCurrency
+}
+
+// Contract months are a month code and a one or two digit year: ESU4, NQZ24
+var contractMonth = regexp.MustCompile(`^(.+?)[FGHJKMNQUVXZ]\d{1,2}$`)
+
+// instrument finds the configured instrument for a ticker, by the ticker
+// itself or by the root of a futures symbol (/ES, ESU4 and ES=F all find ES)
+func (e *Engine) instrument(ticker string) (Instrument, bool) {
+	if in, ok := e.cfg.Instruments[ticker]; ok {
+		return in, true
+	}
+
+	root := strings.TrimSuffix(strings.TrimPrefix(ticker, "/"), "=F")
+	if in, ok := e.cfg.Instruments[root]; ok {
+		return in, true
+	}
+
+	if base, quote, ok := forexPair(ticker); ok {
+		in, ok := e.cfg.Instruments[base+quote]
+		return in, ok
+	}
+
+	if m := contractMonth.FindStringSubmatch(root); m != nil {
+		in, ok := e.cfg.Instruments[m[1]]
+		return in, ok
+	}
+
+	return Instrument{}, false
+}
+
+// roundPrice rounds to the instrument's tick or pip, or the stock tick size
+func (e *Engine) roundPrice(ticker string, price float64) float64 {
+	return e.cfg.Rounding.round(price, e.tick(ticker, price))
+}
+
+// pointValue is what a one point move at price is worth per share, contract
+// or lot, in the account currency
+func (e *Engine) pointValue(ticker string, price float64) (float64, error) {
+	in, ok := e.instrument(ticker)
+	switch {
+	case !ok:
+		// Shares listed abroad move in their venue's currency
+		if _, v, ok := e.cfg.venue(ticker); ok {
+			return e.cfg.currencyRate(v.Currency)
+		}
+		return 1, nil
+	case in.Type == instrumentForex:
+		rate, err := e.cfg.quoteRate(ticker, price)
+		return lotUnits[in.lot()] * rate, err
+	}
+	return in.PointValue(), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import "testing"
+
+func futuresEngine(balance, margin float64) *Engine {
+	cfg := DefaultConfig()
+	cfg.AccountBalance = balance
+	cfg.Instruments = map[string]Instrument{
+		"ES":  {Type: instrumentFuture, TickSize: .25, Multiplier: 50, Margin: margin},
+		"MES": {Type: instrumentFuture, TickSize: .25, TickValue: 1.25},
+	}
+	return NewEngine(cfg, nil)
+}
+
+func TestInstrumentLookup(t *testing.T) {
+	e := futuresEngine(10000, 0)
+
+	for ticker, want := range map[string]float64{"ES": 50, "/ES": 50, "ES=F": 50, "ESU4": 50, "ESZ24": 50, "MESH5": 5} {
+		in, ok := e.instrument(ticker)
+		if !ok || in.PointValue() != want {
+			t.Errorf("%s: got %+v %v, want point value %v", ticker, in, ok, want)
+		}
+	}
+
+	for _, ticker := range []string{"AAPL", "ESX", "NQU4"} {
+		if _, ok := e.instrument(ticker); ok {
+			t.Errorf("%s: want no instrument", ticker)
+		}
+	}
+}
+
+func TestCalculateFutures(t *testing.T) {
+	// 100,000 risk, the stop is 444.50 points or 22,225 per contract away
+	p := futuresEngine(5_000_000, 12000).Calculate("ESU4", -.1, 5000)
+
+	if p.EntryPrice != 5000 || p.StopLossPrice != 4555.5 || p.TakeProfitPrice != 5444.5 {
+		t.Fatalf("prices not on the 0.25 grid: %+v", p)
+	}
+	if p.Shares != 4 || p.Profit != 88900 || p.Margin != 48000 {
+		t.Errorf("got %d contracts, profit %v, margin %v, want 4, 88900 and 48000", p.Shares, p.Profit, p.Margin)
+	}
+
+	// Margin of 2,000,000 a contract leaves room for two
+	p = futuresEngine(5_000_000, 2_000_000).Calculate("ESU4", -.1, 5000)
+	if p.Shares != 2 || p.Margin != 4_000_000 {
+		t.Errorf("margin capped: got %d contracts, margin %v", p.Shares, p.Margin)
+	}
+}
+
+func TestInstrumentValidate(t *testing.T) {
+	for _, in := range []Instrument{
+		{Type: "option", TickSize: .25, Multiplier: 50},
+		{Type: instrumentFuture, Multiplier: 50},
+		{Type: instrumentFuture, TickSize: .25},
+		{Type: instrumentFuture, TickSize: .25, Multiplier: 50, TickValue: 12.5},
+		{Type: instrumentFuture, TickSize: .25, Multiplier: 50, Margin: -1},
+	} {
+		if err := in.Validate("ES"); err == nil {
+			t.Errorf("%+v: want error", in)
+		}
+	}
+}
+
+func forexEngine() *Engine {
+	cfg := DefaultConfig()
+	cfg.Rates = map[string]float64{"JPY": .0065}
+	cfg.Instruments = map[string]Instrument{
+		"EURUSD": {Type: instrumentForex},
+		"USDJPY": {Type: instrumentForex, Lot: "mini"},
+		"EURJPY": {Type: instrumentForex, Lot: "standard"},
+	}
+	return NewEngine(cfg, nil)
+}
+
+func TestCalculateForex(t *testing.T) {
+	e := forexEngine()
+
+	// 200 risk, 970 pips to the stop at 10 cents a pip per micro lot
+	p := e.Calculate("EUR/USD", -.1, 1.0912)
+	if p.StopLossPrice != .9942 || p.TakeProfitPrice != 1.1882 || p.StopPips != 970 || p.TargetPips != 970 {
+		t.Fatalf("prices not on the pip grid: %+v", p)
+	}
+	if p.Lot != "micro" || p.Shares != 2 || p.Profit != 194 {
+		t.Errorf("got %d %s lots, profit %v, want 2 micro and 194", p.Shares, p.Lot, p.Profit)
+	}
+
+	// Based on the account currency, converted with the pair's own price:
+	// 100 yen a pip per mini lot is 63 cents at 158
+	p = e.Calculate("USDJPY=X", .1, 158)
+	if p.StopPips != 1149 || p.Shares != 0 {
+		t.Errorf("USDJPY: got %+v", p)
+	}
+	p = e.Calculate("USDJPY=X", .001, 158)
+	if p.StopPips != 13 || p.Shares != 24 || p.Lot != "mini" {
+		t.Errorf("USDJPY: got %+v, want 13 pips and 24 mini lots", p)
+	}
+
+	// Quoted in a third currency, converted with the configured rate
+	if pv, err := e.pointValue("EURJPY", 170); err != nil || pv != 650 {
+		t.Errorf("EURJPY point value: got %v %v, want 650", pv, err)
+	}
+}
+
+func TestForexNeedsRate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Instruments = map[string]Instrument{"EURGBP": {Type: instrumentForex}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("want error for a pair quoted in a currency without a rate")
+	}
+
+	cfg.AccountCurrency = "GBP"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("GBP account: %v", err)
+	}
+
+	cfg.Instruments = map[string]Instrument{"EURO": {Type: instrumentForex}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("want error for a symbol that isn't a pair")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runLock keeps two runs from writing the same output at once, e.g. the
+// daemon and a manual run. It is an advisory lock on a file next to the
+// output, which the system lets go of when the process ends, however it
+// ends. While held the file says who took it and when.
+type runLock struct {
+	file *os.File
+}
+
+type lockOwner struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Returned by tryLock when another run holds the lock
+var errLocked = errors.New("locked")
+
+// acquireLock takes the lock for outputPath. The file stays when the lock is
+// released, what it says only counts while it's locked.
+func acquireLock(outputPath string) (*runLock, error) {
+	path := outputPath + ".lock"
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating lock %s: %w", path, err)
+	}
+
+	if err := tryLock(f); err != nil {
+		f.Close()
+		if !errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("error locking %s: %w", path, err)
+		}
+
+		held, err := readLock(path)
+		if err != nil {
+			// Taken so recently that the owner isn't written yet
+			return nil, fmt.Errorf("another run holds %s", path)
+		}
+		return nil, fmt.Errorf("another run in progress since %s (pid %d on %s)",
+			held.StartedAt.Local().Format("15:04"), held.PID, held.Host)
+	}
+
+	host, _ := os.Hostname()
+	owner, err := json.Marshal(lockOwner{PID: os.Getpid(), Host: host, StartedAt: time.Now()})
+	if err == nil {
+		err = f.Truncate(0)
+	}
+	if err == nil {
+		_, err = f.WriteAt(owner, 0)
+	}
+	if err != nil {
+		unlock(f)
+		f.Close()
+		return nil, fmt.Errorf("error writing lock %s: %w", path, err)
+	}
+
+	return &runLock{file: f}, nil
+}
+
+func readLock(path string) (lockOwner, error) {
+	var owner lockOwner
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return owner, err
+	}
+
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return owner, fmt.Errorf("unreadable lock: %w", err)
+	}
+
+	return owner, nil
+}
+
+func (l *runLock) Release() error {
+	// Emptied so it doesn't name a run that's over
+	l.file.Truncate(0)
+
+	if err := unlock(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("error unlocking %s: %w", l.file.Name(), err)
+	}
+	return l.file.Close()
+}
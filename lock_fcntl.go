@@ -0,0 +1,25 @@
+//go:build solaris || aix
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// tryLock takes an exclusive fcntl lock on f without waiting for it, these
+// systems have no flock. fcntl locks belong to the process, so they only
+// keep other processes out.
+func tryLock(f *os.File) error {
+	err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart})
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EACCES) {
+		return errLocked
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	return syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &syscall.Flock_t{Type: syscall.F_UNLCK, Whence: io.SeekStart})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunLock(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "opg.json")
+
+	lock, err := acquireLock(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = acquireLock(output)
+	if err == nil || !strings.Contains(err.Error(), "another run in progress since") {
+		t.Fatalf("second run: got %v, want another run in progress", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err = acquireLock(output)
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	lock.Release()
+}
+
+func TestRunLockIgnoresLeftoverFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "opg.json")
+	host, _ := os.Hostname()
+
+	// Left behind by a run that was killed, nothing holds it
+	data, _ := json.Marshal(lockOwner{PID: os.Getpid() + 1, Host: host, StartedAt: time.Now().Add(-time.Hour)})
+	if err := os.WriteFile(output+".lock", data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireLock(output)
+	if err != nil {
+		t.Fatalf("leftover lock file: %v", err)
+	}
+	defer lock.Release()
+
+	// Whatever the file says, a held lock is never taken over
+	if _, err := acquireLock(output); err == nil {
+		t.Fatal("took over a held lock")
+	}
+	if held, err := readLock(output + ".lock"); err != nil || held.PID != os.Getpid() {
+		t.Errorf("lock file names %+v, %v, want this process", held, err)
+	}
+}
+
+func TestRunLockConcurrent(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "opg.json")
+
+	var mu sync.Mutex
+	var held []*runLock
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lock, err := acquireLock(output); err == nil {
+				mu.Lock()
+				held = append(held, lock)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(held) != 1 {
+		t.Errorf("%d runs got the lock, want 1", len(held))
+	}
+	for _, lock := range held {
+		lock.Release()
+	}
+}
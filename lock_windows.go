@@ -0,0 +1,48 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32       = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = kernel32.NewProc("LockFileEx")
+	procUnlockFile = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// The locked byte is far past the owner written in the file. Windows locks
+// are mandatory for the locked range, other runs still have to read it.
+func lockRange() *syscall.Overlapped {
+	return &syscall.Overlapped{OffsetHigh: 0x40000000}
+}
+
+// tryLock takes an exclusive lock on f without waiting for it
+func tryLock(f *os.File) error {
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0,
+		uintptr(unsafe.Pointer(lockRange())))
+	if r != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errLocked
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	r, _, err := procUnlockFile.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(lockRange())))
+	if r != 0 {
+		return nil
+	}
+	return err
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net/http"
 	"os"
-	"slices"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -19,41 +24,44 @@ type Stock struct {
 	OpeningPrice float64
 }
 
-func Load(path string) ([]Stock, error) {
+// Scan streams the CSV file row by row and calls fn for every stock, so even
+// multi-million-row scan files are processed with constant memory
+func Scan(path string, fn func(Stock) error) error {
 	// Open file using the os module
 	f, err := os.Open(path)
 
 	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		return err
 	}
 
 	// Defer closing the file if error occurs
 	defer f.Close()
 
-	// Reader of csv files
-	r := csv.NewReader(f)
-
-	// Read content of the file
-	rows, err := r.ReadAll()
+	// Reader of csv files, the record slice is reused between rows
+	r := csv.NewReader(bufio.NewReader(f))
+	r.ReuseRecord = true
 
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
+	// Skip the first row of the file since its a header
+	if _, err := r.Read(); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
 	}
 
-	// Delete the first row of the file since its a header
-	rows = slices.Delete(rows, 0, 1)
-
-	// Declare variable to store our stock data
-	var stocks []Stock
-
 	// Loop through file and get data in each row
-	for _, row := range rows {
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
 
-		ticker:= row[0]
+		ticker := row[0]
 
-		gap, err := strconv.ParseFloat(row[1], 64) 
+		gap, err := strconv.ParseFloat(row[1], 64)
 		if err != nil {
 			continue
 		}
@@ -63,79 +71,162 @@ func Load(path string) ([]Stock, error) {
 			continue
 		}
 
-		stocks = append(stocks, Stock {
-			Ticker:       ticker, 
+		err = fn(Stock{
+			Ticker:       ticker,
 			Gap:          gap,
 			OpeningPrice: openingPrice,
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	f.Close()
-
-	return stocks, nil
+	return nil
 }
 
-// Money in the trading account
-var accountBalance = 10000.0
-
-// Percentage of balance i can tolerate losing
-var lossTolerance = .02
+// Only gaps of at least 10% are worth trading
+const minGap = .1
 
-// Max amount i can tolerate losing
-var maxLossPerTrade = accountBalance * lossTolerance
-
-// Percentage of gap i want to take as profit
-var profitPercent = .8
+func worthTrading(s Stock) bool {
+	return math.Abs(s.Gap) >= minGap
+}
 
 type Position struct {
-	EntryPrice       float64
-	Shares           int
-	TakeProfitPrice  float64
-	StopLossPrice    float64
-	Profit           float64
+	EntryPrice      float64
+	Shares          int
+	TakeProfitPrice float64
+	StopLossPrice   float64
+	Profit          float64
+
+	// Margin the contracts tie up, for futures
+	Margin float64 `json:",omitempty"`
+
+	// Forex: Shares counts lots of this size, the distances are in pips
+	Lot        string  `json:",omitempty"`
+	StopPips   float64 `json:",omitempty"`
+	TargetPips float64 `json:",omitempty"`
 }
 
-func Calculate(gapPercent, openingPrice float64) Position {
+func (e *Engine) Calculate(ticker string, gapPercent, openingPrice float64) Position {
+	p, _ := e.calculate(ticker, gapPercent, openingPrice)
+	return p
+}
+
+// calculate sizes the position and explains how it got there
+func (e *Engine) calculate(ticker string, gapPercent, openingPrice float64) (Position, *Explanation) {
 	closingPrice := openingPrice / (1 + gapPercent)
 	gapValue := closingPrice - openingPrice
-	profitFromGap := profitPercent * gapValue
+	profitFromGap := e.cfg.ProfitPercent * gapValue
+
+	// Orders can only be placed on the tick grid, so size from the rounded prices
+	entry := e.roundPrice(ticker, openingPrice)
+	stopLoss := e.roundPrice(ticker, openingPrice-profitFromGap)
+	takeProfit := e.roundPrice(ticker, openingPrice+profitFromGap)
+
+	// A share moves one for one with the price, a contract or lot by its point value
+	inst, isContract := e.instrument(ticker)
+	pointValue, err := e.pointValue(ticker, entry)
+	if err != nil {
+		// Foreign listings are only known from the input, their rate can be missing
+		log.Printf("Can't size %s, %v", ticker, err)
+	}
+
+	x := &Explanation{
+		Gap:            gapPercent,
+		OpeningPrice:   openingPrice,
+		PreviousClose:  closingPrice,
+		GapValue:       gapValue,
+		TargetDistance: profitFromGap,
+		Tick:           e.tick(ticker, openingPrice),
+		PointValue:     pointValue,
+		RiskPerShare:   math.Abs(stopLoss-entry) * pointValue,
+		MaxLoss:        e.MaxLossPerTrade(),
+		Filters:        []string{fmt.Sprintf("gap of %.2f%% is at least the %g%% minimum", 100*math.Abs(gapPercent), 100*minGap)},
+	}
+	x.explainSizing(err, unitsOf(inst, isContract))
 
-	stopLoss := openingPrice - profitFromGap
-	takeProfit := openingPrice + profitFromGap
+	shares := 0
+	if risk := x.RiskPerShare; risk > 0 {
+		// The epsilon keeps 200/8.000000000000002 from losing a share
+		shares = int(e.MaxLossPerTrade()/risk + 1e-9)
+	}
+
+	margin := 0.0
+	if isContract && inst.Margin > 0 {
+		affordable := int(e.cfg.AccountBalance / inst.Margin)
+		if affordable < shares {
+			x.Sizing = append(x.Sizing, fmt.Sprintf("capped at %d by margin: balance %.2f / margin %.2f a contract", affordable, e.cfg.AccountBalance, inst.Margin))
+		}
+		shares = min(shares, affordable)
+		margin = float64(shares) * inst.Margin
+	}
 
-	shares := int(maxLossPerTrade / math.Abs(stopLoss-openingPrice))
+	profit := math.Abs(entry-takeProfit) * pointValue * float64(shares)
 
-	profit := math.Abs(openingPrice-takeProfit) * float64(shares)
-	profit = math.Round(profit*100) / 100
+	p := Position{
+		EntryPrice:      entry,
+		Shares:          shares,
+		TakeProfitPrice: takeProfit,
+		StopLossPrice:   stopLoss,
+		Profit:          e.cfg.Rounding.Money(profit),
+		Margin:          margin,
+	}
 
-	return Position {
-		EntryPrice:      math.Round(openingPrice*100) /100,       
-		Shares:          shares,            
-		TakeProfitPrice: math.Round(takeProfit*100) /100,    
-		StopLossPrice:   math.Round(stopLoss*100) /100,
-		Profit:          math.Round(profit*100) /100,         
+	if isContract && inst.Type == instrumentForex {
+		pip := inst.pip(ticker)
+		p.Lot = inst.lot()
+		p.StopPips = math.Round(math.Abs(entry-stopLoss) / pip)
+		p.TargetPips = math.Round(math.Abs(takeProfit-entry) / pip)
 	}
+
+	x.round()
+	return p, x
+}
+
+// Long positions profit from a rise, they take profit above the entry
+func (p Position) Long() bool {
+	return p.TakeProfitPrice > p.EntryPrice
 }
 
 type Selection struct {
-	Ticker   string
+	Ticker string
 	Position
+	Identifiers
+
+	// Exchange and currency of tickers listed outside the US main markets
+	Exchange string `json:",omitempty"`
+	Currency string `json:",omitempty"`
+
 	Articles []Article
+
+	// The math behind the position, with -explain
+	Explanation *Explanation `json:",omitempty"`
+
+	// One ticket per tranche when scaling in
+	Orders []Order `json:",omitempty"`
+
+	// StatusOK, or StatusError with the reason in Error
+	Status string
+	Error  string `json:",omitempty"`
 }
 
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
 const (
 	url          = "https://seeking-alpha.p.rapidapi.com/news/v2/list-by-symbol?size=5&id="
 	apiKeyHeader = "x-rapidapi-key"
-	apiKey       = "your-api-key-here"
 )
 
 type attributes struct {
-	PublishOn time.Time  `json:"publishOn"`
-	Title     string     `json:"title"`
+	PublishOn time.Time `json:"publishOn"`
+	Title     string    `json:"title"`
 }
 
 type seekingAlphaNews struct {
-	Attributes  attributes  `json:"attributes"`
+	Attributes attributes `json:"attributes"`
 }
 
 type seekingAlphaResponse struct {
@@ -143,28 +234,32 @@ type seekingAlphaResponse struct {
 }
 
 type Article struct {
-	PublishOn time.Time  
-	Headline     string    
+	PublishOn time.Time
+	Headline  string
 }
 
-func FetchNews(ticker string) ([]Article, error) {
-	req, err := http.NewRequest(http.MethodGet, url+ticker, nil)
-
+func (e *Engine) FetchNews(ctx context.Context, ticker string) ([]Article, error) {
+	symbol, err := e.cfg.providerSymbol(providerSeekingAlpha, ticker)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add(apiKeyHeader, apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+symbol, nil)
+
+	if err != nil {
+		return nil, err
+	}
 
-	client := &http.Client{}
+	req.Header.Add(apiKeyHeader, e.cfg.APIKey)
 
-	resp, err := client.Do(req)
+	resp, err := e.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, fmt.Errorf("unsuccessful status code %d recieved", resp.StatusCode) 
+		return nil, fmt.Errorf("unsuccessful status code %d recieved", resp.StatusCode)
 	}
 
 	res := &seekingAlphaResponse{}
@@ -173,9 +268,10 @@ func FetchNews(ticker string) ([]Article, error) {
 	var articles []Article
 
 	for _, item := range res.Data {
-		art := Article {
-			PublishOn: item.Attributes.PublishOn,
-			Headline: item.Attributes.Title,
+		art := Article{
+			// The provider answers in its own offset, show every time in one zone
+			PublishOn: item.Attributes.PublishOn.In(e.display),
+			Headline:  item.Attributes.Title,
 		}
 
 		articles = append(articles, art)
@@ -184,81 +280,24 @@ func FetchNews(ticker string) ([]Article, error) {
 	return articles, nil
 }
 
-
-func Deliver(filePath string, selections []Selection) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("error creating file: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	err = encoder.Encode(selections)
-	if err != nil {
-		return fmt.Errorf("error encoding selections: %w", err)
-	}
-
-	return nil
-}
-
 func main() {
-	stocks, err := Load("./opg.csv")
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+	// Ctrl-C and service stops cancel whatever is running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	stocks = slices.DeleteFunc(stocks , func(s Stock) bool {
-		return math.Abs(s.Gap) < .1 
-	})
-
-	
-	selectionsChan := make(chan Selection, len(stocks))
-
-	for _, stock := range stocks {
-		go func(s Stock, selected chan<-Selection) {
-		
-			position := Calculate(s.Gap, s.OpeningPrice)
-			articles, err := FetchNews(s.Ticker)
-
-			if err != nil {
-				log.Printf("error loading news about %s, %v", s.Ticker, err)
-				selected <- Selection{}
-				return
-			} else {
-				log.Printf("Found %d articles about %s", len(articles), s.Ticker)
-			}
-
-			// We provide each selected stock with its calculated position and related articles
-			sel := Selection {
-				Ticker:   s.Ticker,
-				Position: position,
-				Articles: articles,
-			}
-
-			selected <- sel
-
-		}(stock, selectionsChan)
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	var selections []Selection
-
-	for sel := range selectionsChan {
-		selections = append(selections, sel)
-		if len(selections) == len(stocks) {
-			close(selectionsChan)
-		}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Printf("unknown command %q\n", name)
+		os.Exit(2)
 	}
 
-	outputPath := "./opg.json"
-
-	// Output the results
-	err = Deliver(outputPath, selections)
-	if err != nil {
-		log.Printf("Error writing output, %v", err)
-		return
+	if err := cmd(ctx, args); err != nil {
+		log.Println(err)
+		os.Exit(1)
 	}
-
-	log.Printf("Finished writing output to %s\n", outputPath)
-
-}
\ No newline at end of file
+}
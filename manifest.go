@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestConfig asks for a checksum manifest next to the outputs, so whatever
+// acts on the plan can tell it is complete and untouched
+type ManifestConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// PEM encoded (PKCS #8) ed25519 private key, signs the manifest when set
+	SigningKey string `json:"signingKey,omitempty"`
+}
+
+// manifestPath names the manifest after the output: opg.json -> opg.sha256
+func manifestPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".sha256"
+}
+
+// writeManifest writes the checksums of files in sha256sum format, so
+// "sha256sum -c opg.sha256" verifies them too. Files are listed relative to
+// the manifest and have to be in its directory.
+func writeManifest(path string, files []string, signingKey string) error {
+	var b bytes.Buffer
+	for _, f := range files {
+		sum, err := fileSHA256(f)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s  %s\n", sum, filepath.Base(f))
+	}
+
+	// The signature of an earlier manifest would no longer match it
+	if signingKey == "" {
+		if err := os.Remove(path + ".sig"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing old signature: %w", err)
+		}
+	} else {
+		key, err := loadSigningKey(signingKey)
+		if err != nil {
+			return err
+		}
+
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, b.Bytes()))
+		if err := writeFileAtomic(path+".sig", []byte(sig+"\n")); err != nil {
+			return fmt.Errorf("error writing signature: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(path, b.Bytes()); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic replaces path in one step, so whatever reads the manifest
+// never sees half of it
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key %s: %w", path, err)
+	}
+
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is a %T, want ed25519", path, key)
+	}
+
+	return ed, nil
+}
+
+func loadVerifyKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key %s: %w", path, err)
+	}
+
+	ed, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is a %T, want ed25519", path, key)
+	}
+
+	return ed, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", path)
+	}
+
+	return block, nil
+}
+
+// verifyManifest checks the signature, if a public key is given, and then
+// every checksum in the manifest
+func verifyManifest(path string, publicKey ed25519.PublicKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	if publicKey != nil {
+		sig, err := os.ReadFile(path + ".sig")
+		if err != nil {
+			return fmt.Errorf("error reading signature: %w", err)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil || !ed25519.Verify(publicKey, data, raw) {
+			return fmt.Errorf("signature of %s doesn't match", path)
+		}
+	}
+
+	// Every line ends in a newline, a manifest cut short doesn't
+	if len(data) == 0 {
+		return fmt.Errorf("manifest %s lists no files", path)
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		return fmt.Errorf("manifest %s is truncated", path)
+	}
+
+	var errs []error
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		want, name, ok := strings.Cut(scanner.Text(), "  ")
+		if _, err := hex.DecodeString(want); !ok || err != nil || len(want) != sha256.Size*2 || name == "" {
+			return fmt.Errorf("invalid manifest line %q", scanner.Text())
+		}
+
+		got, err := fileSHA256(filepath.Join(filepath.Dir(path), name))
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case got != want:
+			errs = append(errs, fmt.Errorf("%s: checksum doesn't match", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Checks a plan before acting on it, exits non-zero if anything is off
+func verifyCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	manifest := fs.String("manifest", "", "manifest written next to the output, e.g. opg.sha256 (required)")
+	keyPath := fs.String("key", "", "PEM encoded ed25519 public key, checks the signature when given")
+	fs.Parse(args)
+
+	if *manifest == "" {
+		return errors.New("-manifest is required")
+	}
+
+	var key ed25519.PublicKey
+	if *keyPath != "" {
+		var err error
+		if key, err = loadVerifyKey(*keyPath); err != nil {
+			return err
+		}
+	}
+
+	if err := verifyManifest(*manifest, key); err != nil {
+		return err
+	}
+
+	log.Printf("%s verified", *manifest)
+	return nil
+}
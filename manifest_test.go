@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKeyPair(t *testing.T, dir string) (string, ed25519.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, pub
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, pub := writeKeyPair(t, dir)
+
+	output := filepath.Join(dir, "opg.json")
+	report := filepath.Join(dir, "opg.md")
+	os.WriteFile(output, []byte(`[{"Ticker":"MSFT"}]`), 0o644)
+	os.WriteFile(report, []byte("# Trading plan\n"), 0o644)
+
+	manifest := manifestPath(output)
+	if err := writeManifest(manifest, []string{output, report}, keyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(manifest)
+	if !strings.Contains(string(data), "  opg.json\n") || !strings.Contains(string(data), "  opg.md\n") {
+		t.Errorf("manifest doesn't list the outputs:\n%s", data)
+	}
+
+	if err := verifyManifest(manifest, pub); err != nil {
+		t.Fatalf("fresh manifest: %v", err)
+	}
+
+	// A truncated plan fails the checksum
+	os.WriteFile(output, []byte(`[{"Ticker":"MS`), 0o644)
+	if err := verifyManifest(manifest, nil); err == nil || !strings.Contains(err.Error(), "opg.json") {
+		t.Errorf("truncated output: got %v, want a checksum error", err)
+	}
+
+	// A manifest edited to match it fails the signature
+	sum, _ := fileSHA256(output)
+	lines := strings.SplitN(string(data), "\n", 2)
+	os.WriteFile(manifest, []byte(sum+"  opg.json\n"+lines[1]), 0o644)
+	if err := verifyManifest(manifest, nil); err != nil {
+		t.Errorf("checksums alone should pass: %v", err)
+	}
+	if err := verifyManifest(manifest, pub); err == nil {
+		t.Error("want a signature error for an edited manifest")
+	}
+
+	// Someone else's key
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if err := verifyManifest(manifest, other); err == nil {
+		t.Error("want a signature error for another key")
+	}
+}
+
+func TestManifestPath(t *testing.T) {
+	if got := manifestPath("out/opg.json"); got != "out/opg.sha256" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestManifestRejectsIncomplete(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "opg.json")
+	os.WriteFile(output, []byte(`[{"Ticker":"MSFT"}]`), 0o644)
+
+	manifest := manifestPath(output)
+	if err := writeManifest(manifest, []string{output}, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(manifest)
+
+	for name, content := range map[string]string{
+		"empty":         "",
+		"cut mid line":  string(data[:40]),
+		"no newline":    strings.TrimSuffix(string(data), "\n"),
+		"short sum":     "abc123  opg.json\n",
+		"no file name":  string(data[:64]) + "  \n",
+		"only newlines": "\n\n",
+	} {
+		os.WriteFile(manifest, []byte(content), 0o644)
+		if err := verifyManifest(manifest, nil); err == nil {
+			t.Errorf("%s: verified, want an error", name)
+		}
+	}
+}
+
+func TestManifestRewrite(t *testing.T) {
+	dir := t.TempDir()
+	keyPath, _ := writeKeyPair(t, dir)
+
+	output := filepath.Join(dir, "opg.json")
+	os.WriteFile(output, []byte(`[]`), 0o644)
+	manifest := manifestPath(output)
+
+	if err := writeManifest(manifest, []string{output}, keyPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(manifest + ".sig"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signing turned off, the old signature doesn't belong to the new manifest
+	if err := writeManifest(manifest, []string{output}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(manifest + ".sig"); !os.IsNotExist(err) {
+		t.Errorf("stale signature left behind: %v", err)
+	}
+
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftovers) != 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+	if err := verifyManifest(manifest, nil); err != nil {
+		t.Error(err)
+	}
+}
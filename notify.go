@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Notifier delivers a short message to wherever i'm looking
+type Notifier interface {
+	Notify(ctx context.Context, title, message string) error
+}
+
+type TelegramConfig struct {
+	// TELEGRAM_BOT_TOKEN in the environment wins
+	BotToken string `json:"botToken,omitempty"`
+	ChatID   string `json:"chatId"`
+}
+
+// Where alerts go, the log always gets them too
+type NotifyConfig struct {
+	Telegram *TelegramConfig `json:"telegram,omitempty"`
+
+	// SLACK_WEBHOOK_URL in the environment wins
+	SlackWebhook string `json:"slackWebhook,omitempty"`
+
+	Desktop bool `json:"desktop,omitempty"`
+}
+
+// newNotifier builds a notifier that sends to every configured channel
+func newNotifier(cfg NotifyConfig) Notifier {
+	// Notifications are side effects, they don't go through the provider cache or cassettes
+	client := &http.Client{Timeout: 10 * time.Second}
+	notifiers := multiNotifier{logNotifier{}}
+
+	if cfg.Telegram != nil {
+		notifiers = append(notifiers, &telegramNotifier{client: client, token: cfg.Telegram.BotToken, chatID: cfg.Telegram.ChatID})
+	}
+
+	if cfg.SlackWebhook != "" {
+		notifiers = append(notifiers, &slackNotifier{client: client, webhook: cfg.SlackWebhook})
+	}
+
+	if cfg.Desktop {
+		notifiers = append(notifiers, desktopNotifier{})
+	}
+
+	return notifiers
+}
+
+// multiNotifier sends to all its notifiers, one failing doesn't stop the others
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(ctx context.Context, title, message string) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, title, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type logNotifier struct{}
+
+func (logNotifier) Notify(_ context.Context, title, message string) error {
+	log.Printf("ALERT %s: %s", title, message)
+	return nil
+}
+
+const telegramURL = "https://api.telegram.org/bot"
+
+type telegramNotifier struct {
+	client  *http.Client
+	baseURL string
+	token   string
+	chatID  string
+}
+
+func (t *telegramNotifier) Notify(ctx context.Context, title, message string) error {
+	base := t.baseURL
+	if base == "" {
+		base = telegramURL
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"chat_id": t.chatID,
+		"text":    title + "\n" + message,
+	})
+
+	return postJSON(ctx, t.client, base+t.token+"/sendMessage", body, "telegram")
+}
+
+type slackNotifier struct {
+	client  *http.Client
+	webhook string
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, title, message string) error {
+	body, _ := json.Marshal(map[string]string{
+		"text": "*" + title + "*\n" + message,
+	})
+
+	return postJSON(ctx, s.client, s.webhook, body, "slack")
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL holds the bot token / webhook secret, keep it out of the logs
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: unsuccessful status code %d recieved", name, resp.StatusCode)
+	}
+
+	return nil
+}
+
+type desktopNotifier struct{}
+
+func (desktopNotifier) Notify(ctx context.Context, title, message string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.CommandContext(ctx, "notify-send", title, message)
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("desktop: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
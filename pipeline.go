@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Sink receives selections as soon as they are ready. Implementations must
+// make each written selection visible downstream (flush) before returning.
+// Close is called when the run succeeded, Abort instead of it when it failed.
+type Sink interface {
+	Write(Selection) error
+	Close() error
+	Abort() error
+}
+
+// jsonFileSink writes a JSON array one element at a time into a temp file next
+// to the output, and only renames it into place when the run succeeded. A failed
+// or interrupted run leaves the last good plan untouched.
+type jsonFileSink struct {
+	path string
+	file *os.File
+	out  io.WriteCloser
+	w    *bufio.Writer
+	n    int
+}
+
+// NewJSONFileSink writes to filePath, or to filePath plus the encryption's
+// extension (opg.json.age) when encryption is enabled
+func NewJSONFileSink(filePath string, enc Encryption) (*jsonFileSink, error) {
+	filePath += enc.Ext()
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("error creating file: %w", err)
+	}
+
+	var out io.WriteCloser = file
+	if enc.Enabled() {
+		if out, err = newEncryptedFile(file, enc); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+
+	return &jsonFileSink{path: filePath, file: file, out: out, w: bufio.NewWriter(out)}, nil
+}
+
+// Path is where the output ends up
+func (s *jsonFileSink) Path() string {
+	return s.path
+}
+
+func (s *jsonFileSink) Write(sel Selection) error {
+	data, err := json.Marshal(sel)
+	if err != nil {
+		return fmt.Errorf("error encoding selections: %w", err)
+	}
+
+	sep := ","
+	if s.n == 0 {
+		sep = "["
+	}
+	s.n++
+
+	s.w.WriteString(sep)
+	s.w.Write(data)
+
+	return s.w.Flush()
+}
+
+func (s *jsonFileSink) Close() error {
+	if s.n == 0 {
+		s.w.WriteString("[")
+	}
+	s.w.WriteString("]\n")
+
+	if err := s.w.Flush(); err != nil {
+		s.Abort()
+		return fmt.Errorf("error writing file: %w", err)
+	}
+
+	if err := s.out.Close(); err != nil {
+		os.Remove(s.file.Name())
+		return fmt.Errorf("error writing file: %w", err)
+	}
+
+	if err := os.Rename(s.file.Name(), s.path); err != nil {
+		os.Remove(s.file.Name())
+		return fmt.Errorf("error replacing %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+func (s *jsonFileSink) Abort() error {
+	s.out.Close()
+	return os.Remove(s.file.Name())
+}
+
+// ReadSelections loads a plan written by the JSON file sink
+func ReadSelections(filePath string) ([]Selection, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []Selection
+	if err := json.Unmarshal(data, &selections); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", filePath, err)
+	}
+
+	return selections, nil
+}
+
+// Run streams the input through load → filter → resolve, size and enrich →
+// deliver. Each selection reaches the sink as soon as its news is in, nothing
+// is accumulated.
+func (e *Engine) Run(ctx context.Context, inputPath string, sink Sink) (*Summary, error) {
+	summary := newSummary(inputPath)
+
+	// Cancelled by the caller means interrupted, not by a failing sink
+	interrupted := ctx.Err
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	loadErr := make(chan error, 1)
+	stocks := make(chan Stock)
+
+	// Load and filter
+	go func() {
+		defer close(stocks)
+		loadErr <- Scan(inputPath, func(s Stock) error {
+			if !worthTrading(s) {
+				return nil
+			}
+			select {
+			case stocks <- s:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	// Resolve, size and enrich with a bounded number of concurrent requests
+	selections := make(chan Selection)
+	var wg sync.WaitGroup
+	for range e.workers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range stocks {
+				select {
+				case selections <- e.enrich(ctx, s):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(selections)
+	}()
+
+	// Deliver
+	var sinkErr error
+	for sel := range selections {
+		if sinkErr != nil {
+			continue
+		}
+		if err := sink.Write(sel); err != nil {
+			sinkErr = err
+			cancel()
+			continue
+		}
+		summary.add(sel)
+	}
+
+	summary.FinishedAt = time.Now()
+
+	if sinkErr != nil {
+		return summary, sinkErr
+	}
+
+	// Selections finished after the interruption only carry its error, the
+	// plan is incomplete and must not replace the last good one
+	if err := interrupted(); err != nil {
+		return summary, fmt.Errorf("run interrupted: %w", err)
+	}
+
+	if err := <-loadErr; err != nil {
+		return summary, fmt.Errorf("error loading %s: %w", inputPath, err)
+	}
+
+	return summary, nil
+}
+
+// enrich provides the stock with its calculated position and related articles
+func (e *Engine) enrich(ctx context.Context, s Stock) Selection {
+	// Inputs from European brokers are keyed by ISIN or CUSIP rather than
+	// ticker. Tick sizes, instruments and venues are per ticker, so it's
+	// resolved before sizing.
+	ticker, ids, err := e.ids.Resolve(ctx, s.Ticker)
+	if ticker == "" {
+		log.Printf("error resolving %s, %v", s.Ticker, err)
+		return Selection{
+			Ticker:   s.Ticker,
+			Position: e.Calculate(s.Ticker, s.Gap, s.OpeningPrice),
+			Status:   StatusError,
+			Error:    fmt.Sprintf("resolving identifier: %v", err),
+		}
+	}
+	if err != nil {
+		log.Printf("error looking up identifiers of %s, %v", ticker, err)
+	}
+
+	p, x := e.calculate(ticker, s.Gap, s.OpeningPrice)
+	sel := Selection{
+		Ticker:      ticker,
+		Position:    p,
+		Identifiers: ids,
+		Orders:      e.Orders(ticker, p),
+		Status:      StatusOK,
+	}
+	if e.explain {
+		sel.Explanation = x
+	}
+	if _, v, ok := e.cfg.venue(ticker); ok {
+		sel.Exchange, sel.Currency = v.Name, v.Currency
+	}
+
+	articles, err := e.FetchNews(ctx, ticker)
+
+	// The position is still worth having without the news, so keep it and say what went wrong
+	if err != nil {
+		log.Printf("error loading news about %s, %v", ticker, err)
+		sel.Status = StatusError
+		sel.Error = fmt.Sprintf("loading news: %v", err)
+		return sel
+	}
+
+	log.Printf("Found %d articles about %s", len(articles), ticker)
+
+	sel.Articles = articles
+	return sel
+}
+
+func (e *Engine) workers() int {
+	if e.cfg.Concurrency > 0 {
+		return e.cfg.Concurrency
+	}
+
+	return 1
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Collects selections in memory
+type sliceSink struct {
+	selections []Selection
+	closed     bool
+}
+
+func (s *sliceSink) Write(sel Selection) error {
+	s.selections = append(s.selections, sel)
+	return nil
+}
+
+func (s *sliceSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *sliceSink) Abort() error {
+	return nil
+}
+
+func TestRunReplaysCassette(t *testing.T) {
+	c, err := loadCassette("testdata/opg.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine(DefaultConfig(), &http.Client{Transport: &replayingTransport{cassette: c}})
+	sink := &sliceSink{}
+
+	summary, err := engine.Run(context.Background(), "opg.csv", sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Workers finish in any order
+	got := sink.selections
+	slices.SortFunc(got, func(a, b Selection) int { return strings.Compare(a.Ticker, b.Ticker) })
+
+	want := []struct {
+		ticker   string
+		status   string
+		shares   int
+		articles int
+		err      string
+	}{
+		{"AMZN", StatusError, 9, 0, "unsuccessful status code 429"},
+		{"AVGO", StatusError, 0, 0, "no such host"},
+		{"BRK.A", StatusOK, 0, 2, ""},
+		{"MSFT", StatusOK, 13, 2, ""},
+		{"V", StatusOK, 6, 2, ""},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d selections, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		sel := got[i]
+		if sel.Ticker != w.ticker || sel.Status != w.status || sel.Shares != w.shares || len(sel.Articles) != w.articles {
+			t.Errorf("selection %d: got %s %s %d shares %d articles, want %s %s %d shares %d articles",
+				i, sel.Ticker, sel.Status, sel.Shares, len(sel.Articles), w.ticker, w.status, w.shares, w.articles)
+		}
+		if !strings.Contains(sel.Error, w.err) || (w.err == "" && sel.Error != "") {
+			t.Errorf("%s: got error %q, want it to contain %q", sel.Ticker, sel.Error, w.err)
+		}
+	}
+
+	// The position is kept for failed tickers
+	if got[0].EntryPrice != 160.79 {
+		t.Errorf("AMZN entry price: got %v, want 160.79", got[0].EntryPrice)
+	}
+
+	// Provider offsets are normalized to exchange time
+	if at := got[3].Articles[0].PublishOn; at.Location().String() != defaultTimezone || at.Hour() != 19 {
+		t.Errorf("MSFT article: got %s, want 19:35 in %s", at, defaultTimezone)
+	}
+
+	if summary.Selected != 5 || len(summary.Failures) != 2 {
+		t.Errorf("summary: got %d selected %d failures, want 5 and 2", summary.Selected, len(summary.Failures))
+	}
+}
+
+func TestJSONFileSinkKeepsLastPlanOnAbort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opg.json")
+	if err := os.WriteFile(path, []byte("last good plan"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := NewJSONFileSink(path, Encryption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write(Selection{Ticker: "MSFT"})
+	sink.Abort()
+
+	if data, _ := os.ReadFile(path); string(data) != "last good plan" {
+		t.Fatalf("output after abort: got %q", data)
+	}
+
+	sink, err = NewJSONFileSink(path, Encryption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write(Selection{Ticker: "MSFT", Status: StatusOK})
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Selection
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &got); err != nil || len(got) != 1 || got[0].Ticker != "MSFT" {
+		t.Fatalf("output after close: got %q, %v", data, err)
+	}
+
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) != 0 {
+		t.Fatalf("temp files left behind: %v", leftovers)
+	}
+}
+
+// Holds every request until it's cancelled
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+func TestInterruptedRunKeepsLastPlan(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "opg.json")
+	if err := os.WriteFile(output, []byte("last good plan"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.RunsDir = filepath.Join(dir, "runs")
+	engine := NewEngine(cfg, &http.Client{Transport: blockingTransport{}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := runPlan(ctx, engine, &options{}, "opg.csv", output)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the run to report the interruption", err)
+	}
+
+	if data, _ := os.ReadFile(output); string(data) != "last good plan" {
+		t.Errorf("interrupted run replaced the plan with %q", data)
+	}
+}
+
+func TestOutputMetaCarriesTags(t *testing.T) {
+	c, err := loadCassette("testdata/opg.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.RunsDir = filepath.Join(dir, "runs")
+	engine := NewEngine(cfg, &http.Client{Transport: &replayingTransport{cassette: c}})
+
+	opts := &options{tags: listFlag{"fomc-day"}, note: "half size today"}
+	summary, err := runPlan(context.Background(), engine, opts, "opg.csv", filepath.Join(dir, "opg.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "opg.meta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta OutputMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.Run != summary.ID || meta.Selected != summary.Selected || !slices.Equal(meta.Tags, []string{"fomc-day"}) || meta.Note != "half size today" {
+		t.Errorf("got %+v, want the run's %s", meta, summary.ID)
+	}
+
+	// The plan stays a bare array
+	if _, err := ReadSelections(filepath.Join(dir, "opg.json")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const quotesURL = "https://seeking-alpha.p.rapidapi.com/market/get-realtime-prices?symbols="
+
+type seekingAlphaPrice struct {
+	Attributes struct {
+		Identifier string  `json:"identifier"`
+		Last       float64 `json:"last"`
+	} `json:"attributes"`
+}
+
+type seekingAlphaPrices struct {
+	Data []seekingAlphaPrice `json:"data"`
+}
+
+// FetchQuotes returns the last traded price for each ticker in one request.
+// Tickers the provider doesn't know or cover are left out of the result.
+func (e *Engine) FetchQuotes(ctx context.Context, tickers []string) (map[string]float64, error) {
+	// Provider symbols back to our tickers
+	tickerOf := map[string]string{}
+	var symbols []string
+	for _, t := range tickers {
+		symbol, err := e.cfg.providerSymbol(providerSeekingAlpha, t)
+		if err != nil {
+			log.Printf("Warning: no quotes, %v", err)
+			continue
+		}
+		tickerOf[strings.ToUpper(symbol)] = t
+		symbols = append(symbols, symbol)
+	}
+
+	if len(symbols) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, quotesURL+strings.Join(symbols, ","), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add(apiKeyHeader, e.cfg.APIKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unsuccessful status code %d recieved", resp.StatusCode)
+	}
+
+	res := &seekingAlphaPrices{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("error decoding quotes: %w", err)
+	}
+
+	quotes := map[string]float64{}
+	for _, p := range res.Data {
+		ticker, ok := tickerOf[strings.ToUpper(p.Attributes.Identifier)]
+		if ok && p.Attributes.Last > 0 {
+			quotes[ticker] = p.Attributes.Last
+		}
+	}
+
+	return quotes, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message catalogs and number/date formats, one file per language
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+const defaultLanguage = "en"
+
+type Locale struct {
+	Language           string            `json:"-"`
+	DecimalSeparator   string            `json:"decimalSeparator"`
+	ThousandsSeparator string            `json:"thousandsSeparator"`
+	CurrencyAfter      bool              `json:"currencyAfter"`
+	DateFormat         string            `json:"dateFormat"`
+	Messages           map[string]string `json:"messages"`
+
+	// Missing messages come from English
+	fallback *Locale
+}
+
+// LoadLocale finds the catalog for a language tag, "de-AT" falls back to "de"
+func LoadLocale(lang string) (*Locale, error) {
+	if lang == "" {
+		lang = defaultLanguage
+	}
+
+	base, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(lang, "_", "-")), "-")
+	l, err := readLocale(base)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported language %q", lang)
+	}
+
+	if base != defaultLanguage {
+		if l.fallback, err = readLocale(defaultLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func readLocale(lang string) (*Locale, error) {
+	data, err := localeFiles.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Locale{Language: lang}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("error decoding locale %s: %w", lang, err)
+	}
+
+	return l, nil
+}
+
+// T returns the message for key, the key itself if no catalog has it
+func (l *Locale) T(key string) string {
+	if m, ok := l.Messages[key]; ok {
+		return m
+	}
+	if l.fallback != nil {
+		return l.fallback.T(key)
+	}
+	return key
+}
+
+// Number formats v with the locale's separators, e.g. 1,234.50 or 1.234,50
+func (l *Locale) Number(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.ThousandsSeparator)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(l.DecimalSeparator)
+		b.WriteString(frac)
+	}
+
+	return b.String()
+}
+
+func (l *Locale) Date(t time.Time) string {
+	return t.Format(l.DateFormat)
+}
+
+// Ago describes how long before now t was, e.g. "2h ago"
+func (l *Locale) Ago(t, now time.Time) string {
+	d := now.Sub(t)
+
+	switch {
+	case d < time.Minute:
+		return l.T("justNow")
+	case d < time.Hour:
+		return fmt.Sprintf(l.T("minutesAgo"), int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf(l.T("hoursAgo"), int(d/time.Hour))
+	default:
+		return fmt.Sprintf(l.T("daysAgo"), int(d/(24*time.Hour)))
+	}
+}
+
+// WriteReport renders the plan as Markdown, to paste into a group chat or wiki.
+// Times are shown in the engine's display timezone, article ages are relative
+// to the end of the run.
+func WriteReport(w io.Writer, l *Locale, e *Engine, summary *Summary, selections []Selection) error {
+	var b strings.Builder
+	tz := e.DisplayLocation()
+
+	generated := summary.FinishedAt.In(tz)
+	fmt.Fprintf(&b, "# %s\n\n", l.T("title"))
+	fmt.Fprintf(&b, "%s: %s %s  \n", l.T("generated"), l.Date(generated), generated.Format("MST"))
+	fmt.Fprintf(&b, "%s: %s  \n", l.T("input"), summary.Input)
+	if summary.Account != "" {
+		fmt.Fprintf(&b, "%s: %s  \n", l.T("account"), summary.Account)
+	}
+	if len(summary.Tags) > 0 {
+		fmt.Fprintf(&b, "%s: %s  \n", l.T("tags"), strings.Join(summary.Tags, ", "))
+	}
+	if summary.Note != "" {
+		fmt.Fprintf(&b, "%s: %s  \n", l.T("note"), summary.Note)
+	}
+	b.WriteString("\n")
+
+	var planned []Selection
+	for _, sel := range selections {
+		if sel.Ticker != "" {
+			planned = append(planned, sel)
+		}
+	}
+
+	if len(planned) == 0 {
+		fmt.Fprintf(&b, "%s\n", l.T("noSelections"))
+	} else {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+			l.T("ticker"), l.T("direction"), l.T("entry"), l.T("shares"), l.T("target"), l.T("stop"), l.T("profit"))
+		b.WriteString("|---|---|--:|--:|--:|--:|--:|\n")
+
+		total := 0.0
+		for _, sel := range planned {
+			direction := l.T("short")
+			if sel.Long() {
+				direction = l.T("long")
+			}
+
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+				sel.Ticker, direction,
+				e.formatPrice(l, sel.Ticker, sel.EntryPrice), l.Number(float64(sel.Shares), 0),
+				e.formatPrice(l, sel.Ticker, sel.TakeProfitPrice), e.formatPrice(l, sel.Ticker, sel.StopLossPrice),
+				e.formatMoney(l, sel.Profit))
+			total += sel.Profit
+		}
+		fmt.Fprintf(&b, "\n%s: %s\n", l.T("total"), e.formatMoney(l, total))
+
+		fmt.Fprintf(&b, "\n## %s\n", l.T("news"))
+		for _, sel := range planned {
+			fmt.Fprintf(&b, "\n### %s\n\n", sel.Ticker)
+			if len(sel.Articles) == 0 {
+				fmt.Fprintf(&b, "%s\n", l.T("noNews"))
+			}
+			for _, a := range sel.Articles {
+				fmt.Fprintf(&b, "- %s (%s) %s\n", l.Date(a.PublishOn.In(tz)), l.Ago(a.PublishOn, generated), a.Headline)
+			}
+		}
+	}
+
+	if len(summary.Failures) > 0 {
+		fmt.Fprintf(&b, "\n## %s\n\n", l.T("failures"))
+		for _, f := range summary.Failures {
+			fmt.Fprintf(&b, "- %s: %s\n", f.Ticker, f.Error)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// reportPath puts the report next to the output: opg.json -> opg.md
+func reportPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".md"
+}
+
+// writeReportFile returns the name the report got, which has the
+// encryption's extension when it is enabled
+func writeReportFile(path string, l *Locale, e *Engine, summary *Summary, selections []Selection) (string, error) {
+	f, path, err := createOutput(path, e.Config().Encryption)
+	if err != nil {
+		return "", fmt.Errorf("error creating report: %w", err)
+	}
+
+	if err := WriteReport(f, l, e, summary, selections); err != nil {
+		f.Close()
+		return "", fmt.Errorf("error writing report: %w", err)
+	}
+
+	return path, f.Close()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocaleNumber(t *testing.T) {
+	tests := []struct {
+		lang     string
+		v        float64
+		decimals int
+		want     string
+	}{
+		{"en", 1234.5, 2, "1,234.50"},
+		{"en", 576721.27, 2, "576,721.27"},
+		{"en", -1234567, 0, "-1,234,567"},
+		{"en", 12, 2, "12.00"},
+		{"de", 1234.5, 2, "1.234,50"},
+		{"de", -0.001, 2, "0,00"},
+		{"es", 1234567.891, 2, "1.234.567,89"},
+		{"es-MX", 999, 0, "999"},
+	}
+
+	for _, tt := range tests {
+		l, err := LoadLocale(tt.lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.Number(tt.v, tt.decimals); got != tt.want {
+			t.Errorf("%s Number(%v, %d) = %q, want %q", tt.lang, tt.v, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleMessages(t *testing.T) {
+	de, err := LoadLocale("de_DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := de.T("profit"); got != "Gewinn" {
+		t.Errorf("T(profit) = %q, want Gewinn", got)
+	}
+
+	// Missing in German, comes from English
+	delete(de.Messages, "ticker")
+	if got := de.T("ticker"); got != "Ticker" {
+		t.Errorf("fallback T(ticker) = %q, want Ticker", got)
+	}
+	if got := de.T("nope"); got != "nope" {
+		t.Errorf("unknown T(nope) = %q", got)
+	}
+
+	if _, err := LoadLocale("fr"); err == nil {
+		t.Error("want error for a language without a catalog")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	es, err := LoadLocale("es")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := &Summary{
+		Input:      "opg.csv",
+		FinishedAt: time.Date(2024, 7, 9, 9, 21, 0, 0, time.UTC),
+		Failures:   []Failure{{Ticker: "AMZN", Error: "status 429"}},
+	}
+	selections := []Selection{{
+		Ticker: "MSFT",
+		Position: Position{
+			EntryPrice: 1108.86, Shares: 13, TakeProfitPrice: 94.35, StopLossPrice: 123.37, Profit: 188.69,
+		},
+		Articles: []Article{{PublishOn: time.Date(2024, 7, 8, 8, 0, 0, 0, time.UTC), Headline: "MSFT guidance raised"}},
+	}}
+
+	cfg := DefaultConfig()
+	cfg.DisplayTimezone = "UTC"
+
+	var b strings.Builder
+	if err := WriteReport(&b, es, NewEngine(cfg, nil), summary, selections); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"09/07/2024 09:21 UTC",
+		"| MSFT | Corto | 1.108,86 $ | 13 | 94,35 $ | 123,37 $ | 188,69 $ |",
+		"- 08/07/2024 08:00 (hace 1 d) MSFT guidance raised",
+		"- AMZN: status 429",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestLocaleAgo(t *testing.T) {
+	en, err := LoadLocale("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	de, err := LoadLocale("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 7, 9, 9, 21, 0, 0, time.UTC)
+	tests := []struct {
+		l    *Locale
+		ago  time.Duration
+		want string
+	}{
+		{en, 30 * time.Second, "just now"},
+		{en, 5 * time.Minute, "5m ago"},
+		{en, 2*time.Hour + 59*time.Minute, "2h ago"},
+		{en, 50 * time.Hour, "2d ago"},
+		{de, 2 * time.Hour, "vor 2 Std."},
+	}
+
+	for _, tt := range tests {
+		if got := tt.l.Ago(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("%s Ago(%s) = %q, want %q", tt.l.Language, tt.ago, got, tt.want)
+		}
+	}
+}
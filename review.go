@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// What the reviewer made of a selection
+const (
+	reviewApproved = "approved"
+	reviewRejected = "rejected"
+	reviewResized  = "resized"
+)
+
+type ReviewDecision struct {
+	Ticker   string `json:"ticker"`
+	Decision string `json:"decision"`
+
+	// Shares as planned and as delivered, they only differ when resized
+	Planned int `json:"planned"`
+	Shares  int `json:"shares"`
+}
+
+// Reviews of overlapping runs take turns at the terminal
+var reviewMu sync.Mutex
+
+// reviewSink holds the selections back until the run is done, then asks for
+// each one whether to deliver it as planned, resized or not at all. Nothing
+// reaches the wrapped sink before it was approved.
+type reviewSink struct {
+	Sink
+	engine *Engine
+	locale *Locale
+	in     *bufio.Reader
+	out    io.Writer
+
+	selections []Selection
+	decisions  []ReviewDecision
+}
+
+func newReviewSink(sink Sink, engine *Engine, l *Locale, in io.Reader, out io.Writer) *reviewSink {
+	return &reviewSink{Sink: sink, engine: engine, locale: l, in: bufio.NewReader(in), out: out}
+}
+
+func (s *reviewSink) Write(sel Selection) error {
+	s.selections = append(s.selections, sel)
+	return nil
+}
+
+// Close runs the review and delivers what was approved. Without an answer
+// for every selection nothing is delivered and the last plan stays.
+func (s *reviewSink) Close() error {
+	reviewMu.Lock()
+	defer reviewMu.Unlock()
+
+	for i, sel := range s.selections {
+		s.show(i, sel)
+
+		shares, err := s.ask(sel)
+		if err != nil {
+			s.Sink.Abort()
+			return fmt.Errorf("review of %s: %w", sel.Ticker, err)
+		}
+
+		d := ReviewDecision{Ticker: sel.Ticker, Decision: reviewApproved, Planned: sel.Shares, Shares: shares}
+		switch {
+		case shares < 0:
+			d.Decision, d.Shares = reviewRejected, 0
+		case shares != sel.Shares:
+			d.Decision = reviewResized
+			sel = s.engine.resize(sel, shares)
+		}
+		s.decisions = append(s.decisions, d)
+
+		if d.Decision == reviewRejected {
+			continue
+		}
+		if err := s.Sink.Write(sel); err != nil {
+			s.Sink.Abort()
+			return err
+		}
+	}
+
+	return s.Sink.Close()
+}
+
+// rejected counts the selections that weren't delivered
+func (s *reviewSink) rejected() int {
+	n := 0
+	for _, d := range s.decisions {
+		if d.Decision == reviewRejected {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *reviewSink) show(i int, sel Selection) {
+	e, l := s.engine, s.locale
+
+	side := "SHORT"
+	if sel.Long() {
+		side = "LONG"
+	}
+
+	fmt.Fprintf(s.out, "\n[%d/%d] %s %s %d at %s, stop %s, target %s, profit %s\n",
+		i+1, len(s.selections), sel.Ticker, side, sel.Shares,
+		e.formatPrice(l, sel.Ticker, sel.EntryPrice),
+		e.formatPrice(l, sel.Ticker, sel.StopLossPrice),
+		e.formatPrice(l, sel.Ticker, sel.TakeProfitPrice),
+		e.formatMoney(l, sel.Profit))
+
+	if sel.Error != "" {
+		fmt.Fprintf(s.out, "  error: %s\n", sel.Error)
+	}
+
+	for j, a := range sel.Articles {
+		if j == 3 {
+			fmt.Fprintf(s.out, "  and %d more articles\n", len(sel.Articles)-j)
+			break
+		}
+		fmt.Fprintf(s.out, "  %s  %s\n", l.Date(a.PublishOn), a.Headline)
+	}
+}
+
+// ask returns the shares to deliver, -1 to reject
+func (s *reviewSink) ask(sel Selection) (int, error) {
+	for {
+		fmt.Fprint(s.out, "Deliver? [y]es, [n]o or a new size: ")
+
+		line, err := s.in.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "" && err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, errors.New("no answer, review aborted")
+			}
+			return 0, err
+		}
+
+		switch answer {
+		case "y", "yes":
+			return sel.Shares, nil
+		case "n", "no":
+			return -1, nil
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil && n > 0 {
+			return n, nil
+		}
+		fmt.Fprintln(s.out, "Answer y, n or a positive number of shares.")
+	}
+}
+
+// resize sets the selection to a number of shares the planner didn't pick,
+// with the profit, margin and scale-in orders following
+func (e *Engine) resize(sel Selection, shares int) Selection {
+	pointValue, _ := e.pointValue(sel.Ticker, sel.EntryPrice)
+	profit := math.Abs(sel.TakeProfitPrice-sel.EntryPrice) * pointValue * float64(shares)
+	planned := sel.Shares
+
+	sel.Shares = shares
+	sel.Profit = e.cfg.Rounding.Money(profit)
+	if in, ok := e.instrument(sel.Ticker); ok && in.Margin > 0 {
+		sel.Margin = float64(shares) * in.Margin
+	}
+	sel.Orders = e.Orders(sel.Ticker, sel.Position)
+
+	// The steps before still say how the planned count came out, the copy
+	// keeps them from changing under the selection as planned
+	if sel.Explanation != nil {
+		x := *sel.Explanation
+		unit := unitsOf(e.instrument(sel.Ticker))
+		x.Sizing = append(slices.Clone(x.Sizing), fmt.Sprintf("resized in review from %d to %d, risk %.6g a %s × %d = %.2f",
+			planned, shares, x.RiskPerShare, unit, shares, x.RiskPerShare*float64(shares)))
+		sel.Explanation = &x
+	}
+
+	return sel
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func reviewSelections() []Selection {
+	return []Selection{
+		{Ticker: "AMZN", Position: Position{EntryPrice: 160.79, Shares: 9, TakeProfitPrice: 181.73, StopLossPrice: 139.85, Profit: 188.46}},
+		{Ticker: "MSFT", Position: Position{EntryPrice: 108.86, Shares: 13, TakeProfitPrice: 94.35, StopLossPrice: 123.37, Profit: 188.63}},
+		{Ticker: "V", Position: Position{EntryPrice: 250, Shares: 6, TakeProfitPrice: 280, StopLossPrice: 220, Profit: 180}},
+	}
+}
+
+func TestReviewSink(t *testing.T) {
+	l, err := LoadLocale("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner := &sliceSink{}
+	var out bytes.Buffer
+	// Approve AMZN, reject MSFT, V needs a second try before it's resized
+	review := newReviewSink(inner, NewEngine(DefaultConfig(), nil), l, strings.NewReader("y\nn\nmaybe\n5\n"), &out)
+
+	for _, sel := range reviewSelections() {
+		if err := review.Write(sel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(inner.selections) != 0 {
+		t.Fatal("selections delivered before the review")
+	}
+
+	if err := review.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !inner.closed || len(inner.selections) != 2 {
+		t.Fatalf("got %d delivered, closed %v, want 2 delivered", len(inner.selections), inner.closed)
+	}
+	if v := inner.selections[1]; v.Ticker != "V" || v.Shares != 5 || v.Profit != 150 {
+		t.Errorf("resized V: got %d shares, profit %v, want 5 and 150", v.Shares, v.Profit)
+	}
+
+	want := []ReviewDecision{
+		{Ticker: "AMZN", Decision: reviewApproved, Planned: 9, Shares: 9},
+		{Ticker: "MSFT", Decision: reviewRejected, Planned: 13, Shares: 0},
+		{Ticker: "V", Decision: reviewResized, Planned: 6, Shares: 5},
+	}
+	for i, d := range review.decisions {
+		if d != want[i] {
+			t.Errorf("decision %d: got %+v, want %+v", i, d, want[i])
+		}
+	}
+	if review.rejected() != 1 {
+		t.Errorf("got %d rejected, want 1", review.rejected())
+	}
+
+	if !strings.Contains(out.String(), "[2/3] MSFT SHORT 13 at $108.86, stop $123.37, target $94.35, profit $188.63") {
+		t.Errorf("MSFT not shown as expected:\n%s", out.String())
+	}
+}
+
+func TestReviewSinkAbortsWithoutAnswers(t *testing.T) {
+	l, _ := LoadLocale("en")
+
+	inner := &sliceSink{}
+	review := newReviewSink(inner, NewEngine(DefaultConfig(), nil), l, strings.NewReader("y\n"), &bytes.Buffer{})
+	for _, sel := range reviewSelections() {
+		review.Write(sel)
+	}
+
+	if err := review.Close(); err == nil || !strings.Contains(err.Error(), "MSFT") {
+		t.Fatalf("got %v, want the review of MSFT to fail", err)
+	}
+	if inner.closed {
+		t.Error("plan delivered from an unfinished review")
+	}
+}
+
+func TestResizeExplains(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+
+	p, x := e.calculate("MSFT", .1, 108.86)
+	planned := Selection{Ticker: "MSFT", Position: p, Explanation: x}
+
+	sel := e.resize(planned, 10)
+
+	want := []string{
+		"max loss 200.00 / risk 7.92 a share = 25.25, rounded down to 25",
+		"resized in review from 25 to 10, risk 7.92 a share × 10 = 79.20",
+	}
+	if got := sel.Explanation.Sizing; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got sizing %q, want %q", got, want)
+	}
+	if len(planned.Explanation.Sizing) != 1 {
+		t.Errorf("resizing changed the planned explanation to %q", planned.Explanation.Sizing)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Exposure breaks down what a plan puts at risk. Risk is the loss if a stop
+// is hit, notional the value of the position at entry, both in the account
+// currency.
+type Exposure struct {
+	Balance float64
+
+	LongNotional  float64
+	ShortNotional float64
+	LongRisk      float64
+	ShortRisk     float64
+
+	Sectors []SectorExposure
+
+	// Name with the largest loss at its stop
+	LargestTicker string
+	LargestRisk   float64
+}
+
+type SectorExposure struct {
+	Sector   string
+	Notional float64
+	Risk     float64
+}
+
+func (x Exposure) Gross() float64 { return x.LongNotional + x.ShortNotional }
+func (x Exposure) Net() float64   { return x.LongNotional - x.ShortNotional }
+
+// WorstCase is the loss if every stop is hit
+func (x Exposure) WorstCase() float64 { return x.LongRisk + x.ShortRisk }
+
+// Exposure adds up the positions of the plan. Selections without shares
+// don't trade and are left out.
+func (e *Engine) Exposure(selections []Selection) Exposure {
+	x := Exposure{Balance: e.cfg.AccountBalance}
+	sectors := map[string]*SectorExposure{}
+
+	for _, sel := range selections {
+		if sel.Shares == 0 {
+			continue
+		}
+
+		pointValue, _ := e.pointValue(sel.Ticker, sel.EntryPrice)
+
+		notional := sel.EntryPrice * float64(sel.Shares) * pointValue
+		risk := e.risk(sel)
+
+		if sel.Long() {
+			x.LongNotional += notional
+			x.LongRisk += risk
+		} else {
+			x.ShortNotional += notional
+			x.ShortRisk += risk
+		}
+
+		name := e.cfg.Sectors[sel.Ticker]
+		if name == "" {
+			name = "Unknown"
+		}
+		s, ok := sectors[name]
+		if !ok {
+			s = &SectorExposure{Sector: name}
+			sectors[name] = s
+		}
+		s.Notional += notional
+		s.Risk += risk
+
+		if risk > x.LargestRisk {
+			x.LargestTicker, x.LargestRisk = sel.Ticker, risk
+		}
+	}
+
+	for _, s := range sectors {
+		x.Sectors = append(x.Sectors, *s)
+	}
+	sort.Slice(x.Sectors, func(i, j int) bool { return x.Sectors[i].Risk > x.Sectors[j].Risk })
+
+	return x
+}
+
+// risk is what the selection loses if its stops are hit. Scale-in tickets
+// are counted with their own entry and stop.
+func (e *Engine) risk(sel Selection) float64 {
+	pointValue, _ := e.pointValue(sel.Ticker, sel.EntryPrice)
+
+	if len(sel.Orders) == 0 {
+		return math.Abs(sel.EntryPrice-sel.StopLossPrice) * float64(sel.Shares) * pointValue
+	}
+
+	var risk float64
+	for _, o := range sel.Orders {
+		entry := sel.EntryPrice
+		if o.TriggerPrice > 0 {
+			entry = o.TriggerPrice
+		}
+		risk += math.Abs(entry-o.StopLossPrice) * float64(o.Shares) * pointValue
+	}
+	return risk
+}
+
+// Prints the exposure of a plan and fails when the worst case is too much,
+// a last check before placing the orders
+func riskCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("risk", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON config file")
+	account := fs.String("account", "", "account the plan was sized for")
+	planPath := fs.String("plan", "./opg.json", "selections to check, as written by run")
+	maxRisk := fs.Float64("max", -1, "fail if every stop hitting would lose more than this part of the balance, e.g. 0.06; the config's maxPortfolioRisk by default")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *account != "" {
+		if cfg, err = cfg.forAccount(*account); err != nil {
+			return err
+		}
+	}
+	if *maxRisk < 0 {
+		*maxRisk = cfg.MaxPortfolioRisk
+	}
+
+	selections, err := ReadSelections(*planPath)
+	if err != nil {
+		return err
+	}
+
+	l, err := LoadLocale(cfg.Language)
+	if err != nil {
+		return err
+	}
+
+	e := NewEngine(cfg, nil)
+	x := e.Exposure(selections)
+	money := func(v float64) string { return e.formatMoney(l, v) }
+	pct := func(v float64) string { return l.Number(100*v/x.Balance, 1) + "%" }
+
+	fmt.Printf("Gross notional   %14s  %7s of balance\n", money(x.Gross()), pct(x.Gross()))
+	fmt.Printf("Net notional     %14s  %7s\n", money(x.Net()), pct(x.Net()))
+	fmt.Printf("Long             %14s  risk %12s\n", money(x.LongNotional), money(x.LongRisk))
+	fmt.Printf("Short            %14s  risk %12s\n", money(x.ShortNotional), money(x.ShortRisk))
+	if x.LargestTicker != "" {
+		fmt.Printf("Largest risk     %14s  %7s  %s\n", money(x.LargestRisk), pct(x.LargestRisk), x.LargestTicker)
+	}
+
+	fmt.Println("\nSector                     Notional          Risk")
+	for _, s := range x.Sectors {
+		fmt.Printf("%-20s %14s %13s\n", s.Sector, money(s.Notional), money(s.Risk))
+	}
+
+	fmt.Printf("\nWorst case (every stop hit)  %s  %s of balance\n", money(x.WorstCase()), pct(x.WorstCase()))
+
+	if *maxRisk > 0 && x.WorstCase() > *maxRisk*x.Balance {
+		return fmt.Errorf("worst case loss %s is more than %s of the balance", money(x.WorstCase()), pct(*maxRisk*x.Balance))
+	}
+
+	return nil
+}
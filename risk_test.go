@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExposure(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Sectors = map[string]string{"MSFT": "Technology", "NVDA": "Technology"}
+	cfg.Instruments = map[string]Instrument{"ES": {Type: instrumentFuture, TickSize: .25, Multiplier: 50}}
+	e := NewEngine(cfg, nil)
+
+	x := e.Exposure([]Selection{
+		// Long 10 at 100, stop 95: 1,000 notional, 50 risk
+		{Ticker: "NVDA", Position: Position{EntryPrice: 100, Shares: 10, TakeProfitPrice: 110, StopLossPrice: 95}},
+		// Short 20 at 50, stop 52: 1,000 notional, 40 risk
+		{Ticker: "MSFT", Position: Position{EntryPrice: 50, Shares: 20, TakeProfitPrice: 45, StopLossPrice: 52}},
+		// Long 1 ES at 5000, stop 4999: 250,000 notional, 50 risk
+		{Ticker: "ESU4", Position: Position{EntryPrice: 5000, Shares: 1, TakeProfitPrice: 5002, StopLossPrice: 4999}},
+		// Doesn't trade
+		{Ticker: "BRK.A", Position: Position{EntryPrice: 600000, TakeProfitPrice: 550000, StopLossPrice: 650000}},
+	})
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	if !near(x.LongNotional, 251000) || !near(x.ShortNotional, 1000) || !near(x.Gross(), 252000) || !near(x.Net(), 250000) {
+		t.Errorf("notional: got long %v short %v", x.LongNotional, x.ShortNotional)
+	}
+	if !near(x.LongRisk, 100) || !near(x.ShortRisk, 40) || !near(x.WorstCase(), 140) {
+		t.Errorf("risk: got long %v short %v", x.LongRisk, x.ShortRisk)
+	}
+	if x.LargestTicker != "NVDA" || !near(x.LargestRisk, 50) {
+		t.Errorf("largest: got %s %v", x.LargestTicker, x.LargestRisk)
+	}
+
+	if len(x.Sectors) != 2 || x.Sectors[0].Sector != "Technology" || !near(x.Sectors[0].Risk, 90) || x.Sectors[1].Sector != "Unknown" {
+		t.Errorf("sectors: got %+v", x.Sectors)
+	}
+}
+
+func TestExposureCountsScaleInTickets(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+
+	x := e.Exposure([]Selection{{
+		Ticker:   "X",
+		Position: Position{EntryPrice: 100, Shares: 20, TakeProfitPrice: 108, StopLossPrice: 92},
+		Orders: []Order{
+			{Shares: 10, StopLossPrice: 92},
+			// Entered at 102 with a break-even stop at 100
+			{Shares: 10, TriggerPrice: 102, StopLossPrice: 100},
+		},
+	}})
+
+	if x.WorstCase() != 100 {
+		t.Errorf("got worst case %v, want 80 + 20", x.WorstCase())
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	roundHalfUp   = "half-up"
+	roundBankers  = "bankers"
+	roundTruncate = "truncate"
+)
+
+// Rounding controls how prices and money are rounded. Prices go to the
+// instrument's tick size, money always to cents.
+type Rounding struct {
+	// "half-up" (default), "bankers" (half to even) or "truncate" (toward zero)
+	Mode string `json:"mode,omitempty"`
+
+	// Tick size for every ticker, zero uses the US equity rule: $0.0001 below
+	// $1 and $0.01 above
+	TickSize float64 `json:"tickSize,omitempty"`
+
+	// Per ticker tick sizes, e.g. {"XYZ": 0.05}
+	TickSizes map[string]float64 `json:"tickSizes,omitempty"`
+}
+
+func (r Rounding) Validate() error {
+	switch r.Mode {
+	case "", roundHalfUp, roundBankers, roundTruncate:
+	default:
+		return fmt.Errorf("rounding.mode must be %q, %q or %q, got %q", roundHalfUp, roundBankers, roundTruncate, r.Mode)
+	}
+
+	if r.TickSize < 0 {
+		return fmt.Errorf("rounding.tickSize must not be negative, got %v", r.TickSize)
+	}
+	for ticker, tick := range r.TickSizes {
+		if tick <= 0 {
+			return fmt.Errorf("rounding.tickSizes.%s must be positive, got %v", ticker, tick)
+		}
+	}
+
+	return nil
+}
+
+// Tick returns the price increment ticker trades in at price
+func (r Rounding) Tick(ticker string, price float64) float64 {
+	if tick, ok := r.TickSizes[ticker]; ok {
+		return tick
+	}
+	if r.TickSize > 0 {
+		return r.TickSize
+	}
+
+	// Reg NMS allows sub-penny quotes below a dollar
+	if price < 1 {
+		return .0001
+	}
+	return .01
+}
+
+// Price rounds a price to a whole number of ticks
+func (r Rounding) Price(ticker string, price float64) float64 {
+	return r.round(price, r.Tick(ticker, price))
+}
+
+// Money rounds an amount to cents
+func (r Rounding) Money(v float64) float64 {
+	return r.round(v, .01)
+}
+
+func (r Rounding) round(v, step float64) float64 {
+	// Take off float noise first, 1.005/.01 is 100.49999999999999
+	n := math.Round(v/step*1e6) / 1e6
+
+	switch r.Mode {
+	case roundBankers:
+		n = math.RoundToEven(n)
+	case roundTruncate:
+		n = math.Trunc(n)
+	default:
+		n = math.Round(n)
+	}
+
+	// Dividing by the inverse keeps 0.05 ticks from printing as 1.2000000000000002
+	if inv := 1 / step; inv == math.Trunc(inv) {
+		return n / inv
+	}
+	return n * step
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestRoundingPrice(t *testing.T) {
+	tests := []struct {
+		r      Rounding
+		ticker string
+		price  float64
+		want   float64
+	}{
+		{Rounding{}, "MSFT", 108.865, 108.87},
+		{Rounding{}, "MSFT", 1.005, 1.01},
+		{Rounding{Mode: roundBankers}, "MSFT", 108.865, 108.86},
+		{Rounding{Mode: roundBankers}, "MSFT", 108.875, 108.88},
+		{Rounding{Mode: roundTruncate}, "MSFT", 108.869, 108.86},
+
+		// Sub-penny below a dollar
+		{Rounding{}, "PENNY", .123456, .1235},
+
+		{Rounding{TickSize: .05}, "MSFT", 1.22, 1.2},
+		{Rounding{TickSize: .05}, "MSFT", 1.23, 1.25},
+		{Rounding{TickSize: .25}, "ES", 5001.13, 5001.25},
+		{Rounding{TickSize: .05, TickSizes: map[string]float64{"MSFT": .01}}, "MSFT", 1.23, 1.23},
+	}
+
+	for _, tt := range tests {
+		if got := tt.r.Price(tt.ticker, tt.price); got != tt.want {
+			t.Errorf("%+v Price(%s, %v) = %v, want %v", tt.r, tt.ticker, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestRoundingMoney(t *testing.T) {
+	if got := (Rounding{Mode: roundBankers}).Money(188.685); got != 188.68 {
+		t.Errorf("bankers Money = %v, want 188.68", got)
+	}
+	if got := (Rounding{Mode: roundTruncate}).Money(188.689); got != 188.68 {
+		t.Errorf("truncate Money = %v, want 188.68", got)
+	}
+	if got := (Rounding{Mode: roundTruncate}).Money(-1.239); got != -1.23 {
+		t.Errorf("truncate Money = %v, want -1.23", got)
+	}
+}
+
+func TestRoundingValidate(t *testing.T) {
+	for _, r := range []Rounding{
+		{Mode: "up"},
+		{TickSize: -1},
+		{TickSizes: map[string]float64{"X": 0}},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("%+v: want error", r)
+		}
+	}
+}
+
+func TestCalculateOnTicks(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rounding = Rounding{TickSize: .05}
+	e := NewEngine(cfg, nil)
+
+	// 10% gap up from 100.12: 8.01 from the open to the target and the stop
+	p := e.Calculate("X", .1, 110.13)
+	if p.EntryPrice != 110.15 || p.TakeProfitPrice != 102.1 || p.StopLossPrice != 118.15 {
+		t.Fatalf("prices not on the tick grid: %+v", p)
+	}
+
+	// Risk is measured from the rounded prices: 200 / 8.00 shares, 8.05 each to gain
+	if p.Shares != 25 {
+		t.Errorf("shares = %d, want 25", p.Shares)
+	}
+	if p.Profit != 201.25 {
+		t.Errorf("profit = %v, want 201.25", p.Profit)
+	}
+}
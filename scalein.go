@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// ScaleIn splits a position into staged entries, e.g. half at the open and
+// half once the move is confirmed
+type ScaleIn struct {
+	Tranches []Tranche `json:"tranches"`
+}
+
+type Tranche struct {
+	// Part of the position's shares, the fractions add up to 1
+	Fraction float64 `json:"fraction"`
+
+	// How far toward the target the price has to get before this tranche is
+	// entered, 0.25 is a quarter of the way. Zero enters at the open.
+	Confirm float64 `json:"confirm,omitempty"`
+
+	// Where this tranche's stop goes, from the position's stop (0) up to its
+	// entry price (1, break-even)
+	StopShift float64 `json:"stopShift,omitempty"`
+}
+
+func (s ScaleIn) Validate() error {
+	if len(s.Tranches) == 0 {
+		return fmt.Errorf("scaleIn needs at least one tranche")
+	}
+
+	sum := 0.0
+	for i, t := range s.Tranches {
+		if t.Fraction <= 0 {
+			return fmt.Errorf("scaleIn.tranches[%d].fraction must be positive, got %v", i, t.Fraction)
+		}
+		if t.Confirm < 0 || t.Confirm >= 1 {
+			return fmt.Errorf("scaleIn.tranches[%d].confirm must be at least 0 and below 1, got %v", i, t.Confirm)
+		}
+		if t.StopShift < 0 || t.StopShift > 1 {
+			return fmt.Errorf("scaleIn.tranches[%d].stopShift must be between 0 and 1, got %v", i, t.StopShift)
+		}
+		sum += t.Fraction
+	}
+
+	if math.Abs(sum-1) > 1e-9 {
+		return fmt.Errorf("scaleIn fractions add up to %v, want 1", sum)
+	}
+
+	return nil
+}
+
+// Order types of the tickets
+const (
+	orderMarket = "market"
+	orderStop   = "stop"
+)
+
+// Order is one ticket to place with the broker
+type Order struct {
+	Tranche int
+	Side    string
+	Type    string
+	Shares  int
+
+	// Price that triggers a stop entry, zero for market orders
+	TriggerPrice    float64 `json:",omitempty"`
+	StopLossPrice   float64
+	TakeProfitPrice float64
+}
+
+// Orders splits the position into one ticket per tranche. Leftover shares
+// from rounding down go to the first tranche, tranches that end up with no
+// shares are left out.
+func (e *Engine) Orders(ticker string, p Position) []Order {
+	if e.cfg.ScaleIn == nil || p.Shares == 0 {
+		return nil
+	}
+
+	side := "sell"
+	if p.Long() {
+		side = "buy"
+	}
+
+	tranches := e.cfg.ScaleIn.Tranches
+	shares := make([]int, len(tranches))
+	left := p.Shares
+	for i, t := range tranches {
+		shares[i] = int(float64(p.Shares) * t.Fraction)
+		left -= shares[i]
+	}
+	shares[0] += left
+
+	var orders []Order
+	for i, t := range tranches {
+		if shares[i] == 0 {
+			continue
+		}
+
+		o := Order{
+			Tranche:         i + 1,
+			Side:            side,
+			Type:            orderMarket,
+			Shares:          shares[i],
+			StopLossPrice:   e.roundPrice(ticker, p.StopLossPrice+t.StopShift*(p.EntryPrice-p.StopLossPrice)),
+			TakeProfitPrice: p.TakeProfitPrice,
+		}
+
+		// A stop order only fills once the price has come our way
+		if t.Confirm > 0 {
+			o.Type = orderStop
+			o.TriggerPrice = e.roundPrice(ticker, p.EntryPrice+t.Confirm*(p.TakeProfitPrice-p.EntryPrice))
+		}
+
+		orders = append(orders, o)
+	}
+
+	return orders
+}
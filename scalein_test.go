@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrdersSplitPosition(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ScaleIn = &ScaleIn{Tranches: []Tranche{
+		{Fraction: .5},
+		{Fraction: .5, Confirm: .25, StopShift: 1},
+	}}
+	e := NewEngine(cfg, nil)
+
+	long := Position{EntryPrice: 100, Shares: 25, TakeProfitPrice: 108, StopLossPrice: 92}
+	want := []Order{
+		{Tranche: 1, Side: "buy", Type: orderMarket, Shares: 13, StopLossPrice: 92, TakeProfitPrice: 108},
+		{Tranche: 2, Side: "buy", Type: orderStop, Shares: 12, TriggerPrice: 102, StopLossPrice: 100, TakeProfitPrice: 108},
+	}
+	if got := e.Orders("X", long); !reflect.DeepEqual(got, want) {
+		t.Errorf("long:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Shorts confirm below the entry and move the stop down
+	short := Position{EntryPrice: 108.86, Shares: 13, TakeProfitPrice: 94.35, StopLossPrice: 123.37}
+	got := e.Orders("MSFT", short)
+	if len(got) != 2 || got[1].Side != "sell" || got[1].TriggerPrice != 105.23 || got[1].StopLossPrice != 108.86 {
+		t.Errorf("short: got %+v", got)
+	}
+}
+
+func TestOrdersDropEmptyTranches(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ScaleIn = &ScaleIn{Tranches: []Tranche{{Fraction: .8}, {Fraction: .2, Confirm: .5}}}
+	e := NewEngine(cfg, nil)
+
+	got := e.Orders("X", Position{EntryPrice: 100, Shares: 3, TakeProfitPrice: 108, StopLossPrice: 92})
+	if len(got) != 1 || got[0].Shares != 3 {
+		t.Errorf("got %+v, want one ticket for all 3 shares", got)
+	}
+
+	if got := e.Orders("X", Position{EntryPrice: 100}); got != nil {
+		t.Errorf("no shares: got %+v", got)
+	}
+}
+
+func TestScaleInValidate(t *testing.T) {
+	for _, s := range []ScaleIn{
+		{},
+		{Tranches: []Tranche{{Fraction: .5}, {Fraction: .4}}},
+		{Tranches: []Tranche{{Fraction: 1, Confirm: 1}}},
+		{Tranches: []Tranche{{Fraction: 1, StopShift: 1.5}}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%+v: want error", s)
+		}
+	}
+
+	if err := (ScaleIn{Tranches: []Tranche{{Fraction: .7}, {Fraction: .2}, {Fraction: .1}}}).Validate(); err != nil {
+		t.Errorf("fractions adding up to 1 with float error: %v", err)
+	}
+}
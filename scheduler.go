@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Servers often ship without a zoneinfo database, the schedules still need America/New_York
+	_ "time/tzdata"
+)
+
+const defaultTimezone = "America/New_York"
+
+// Schedule runs a scan whenever its cron expression matches, in the given timezone
+type Schedule struct {
+	Name     string `json:"name,omitempty"`
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone,omitempty"`
+
+	// Override the -input/-output flags for this schedule
+	Input  string `json:"input,omitempty"`
+	Output string `json:"output,omitempty"`
+
+	// A run missed by at most this much (daemon down, machine asleep) is
+	// made up for once on startup. Zero never catches up.
+	CatchUp Duration `json:"catchUp,omitempty"`
+
+	// Weekends, market holidays and closures are skipped unless this is set
+	RunOnClosedDays bool `json:"runOnClosedDays,omitempty"`
+}
+
+type scheduledJob struct {
+	Schedule
+	expr *cronExpr
+	loc  *time.Location
+}
+
+func (j *scheduledJob) name() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	return j.Cron
+}
+
+type scheduler struct {
+	jobs      []*scheduledJob
+	statePath string
+	calendar  *Calendar
+
+	// Last run per job name, persisted so catch-up survives restarts
+	lastRun map[string]time.Time
+
+	run func(ctx context.Context, job *scheduledJob) error
+	now func() time.Time
+}
+
+func newScheduler(schedules []Schedule, statePath string, calendar *Calendar, run func(context.Context, *scheduledJob) error) (*scheduler, error) {
+	if len(schedules) == 0 {
+		return nil, errors.New("no schedules configured")
+	}
+
+	s := &scheduler{
+		statePath: statePath,
+		calendar:  calendar,
+		lastRun:   map[string]time.Time{},
+		run:       run,
+		now:       time.Now,
+	}
+
+	for _, sch := range schedules {
+		expr, err := parseCron(sch.Cron)
+		if err != nil {
+			return nil, err
+		}
+
+		tz := sch.Timezone
+		if tz == "" {
+			tz = defaultTimezone
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", sch.Cron, err)
+		}
+
+		s.jobs = append(s.jobs, &scheduledJob{Schedule: sch, expr: expr, loc: loc})
+	}
+
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &s.lastRun); err != nil {
+			log.Printf("Ignoring broken scheduler state %s, %v", statePath, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Run blocks, firing jobs at their scheduled times until ctx is done
+func (s *scheduler) Run(ctx context.Context) error {
+	s.catchUp(ctx)
+
+	for {
+		job, at := s.next(s.now())
+		if job == nil {
+			return errors.New("no schedule will ever fire again")
+		}
+
+		log.Printf("Next run %q at %s", job.name(), at.Format("Mon 2006-01-02 15:04 MST"))
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		s.fire(ctx, job, at)
+	}
+}
+
+// next finds the job that is due first after now
+func (s *scheduler) next(now time.Time) (*scheduledJob, time.Time) {
+	var first *scheduledJob
+	var at time.Time
+
+	for _, job := range s.jobs {
+		t := job.expr.Next(now.In(job.loc))
+		if t.IsZero() {
+			continue
+		}
+		if first == nil || t.Before(at) {
+			first, at = job, t
+		}
+	}
+
+	return first, at
+}
+
+// catchUp runs each job whose most recent scheduled time was missed, if that
+// was within its catch-up window. Several missed runs are only made up once.
+func (s *scheduler) catchUp(ctx context.Context) {
+	now := s.now()
+
+	for _, job := range s.jobs {
+		last, ok := s.lastRun[job.name()]
+		if !ok || job.CatchUp <= 0 {
+			continue
+		}
+
+		var missed time.Time
+		for t := job.expr.Next(last.In(job.loc)); !t.IsZero() && !t.After(now); t = job.expr.Next(t) {
+			missed = t
+		}
+
+		if missed.IsZero() {
+			continue
+		}
+
+		if now.Sub(missed) > time.Duration(job.CatchUp) {
+			log.Printf("Skipping missed run %q from %s, outside the %s catch-up window",
+				job.name(), missed.Format("Mon 15:04 MST"), time.Duration(job.CatchUp))
+			continue
+		}
+
+		log.Printf("Catching up missed run %q from %s", job.name(), missed.Format("Mon 15:04 MST"))
+		s.fire(ctx, job, missed)
+	}
+}
+
+func (s *scheduler) fire(ctx context.Context, job *scheduledJob, at time.Time) {
+	session := Session{Open: true}
+	if s.calendar != nil && !job.RunOnClosedDays {
+		session = s.calendar.Session(at)
+	}
+
+	if !session.Open {
+		log.Printf("Skipping run %q, market closed on %s (%s)", job.name(), session.Date.Format("Mon 2006-01-02"), session.Reason)
+	} else if err := s.run(ctx, job); err != nil && ctx.Err() == nil {
+		log.Printf("Scheduled run %q failed, %v", job.name(), err)
+	}
+
+	s.lastRun[job.name()] = at
+	if err := s.saveState(); err != nil {
+		log.Printf("Error saving scheduler state, %v", err)
+	}
+}
+
+func (s *scheduler) saveState() error {
+	data, err := json.MarshalIndent(s.lastRun, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.statePath, data, 0o644)
+}
+
+// Runs the configured schedules until stopped, instead of relying on an external cron
+func daemonCommand(ctx context.Context, args []string) error {
+	var opts options
+
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	opts.register(fs)
+	listen := fs.String("listen", "", "serve /healthz and /readyz on this address, e.g. :8080")
+	fs.Parse(args)
+
+	engine, done, err := opts.setup()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	cfg := engine.Config()
+
+	status := newHealth()
+	status.setConfigLoaded()
+
+	if *listen != "" {
+		go func() {
+			log.Printf("Serving health checks on %s", *listen)
+			if err := serve(ctx, *listen, status.Handler()); err != nil {
+				log.Printf("Error serving health checks, %v", err)
+			}
+		}()
+	}
+	statePath := filepath.Join(cfg.RunsDir, "scheduler.json")
+
+	calendar, err := NewCalendar(cfg.Holidays)
+	if err != nil {
+		return err
+	}
+
+	sched, err := newScheduler(cfg.Schedules, statePath, calendar, func(ctx context.Context, job *scheduledJob) error {
+		input, output := opts.inputPath, opts.outputPath
+		if job.Input != "" {
+			input = job.Input
+		}
+		if job.Output != "" {
+			output = job.Output
+		}
+
+		if !opts.ignoreWindow {
+			if err := engine.Config().checkWindow(time.Now()); err != nil {
+				status.recordRun(nil, err)
+				return err
+			}
+		}
+
+		log.Printf("Starting scheduled run %q", job.name())
+		opts.rewind()
+		summary, err := runOnce(ctx, engine, &opts, input, output)
+		status.recordRun(summary, err)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = sched.Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		log.Println("Daemon stopped")
+		return nil
+	}
+
+	return err
+}
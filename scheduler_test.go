@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSchedulerCatchUp(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+
+	// Scheduled every weekday at 09:20 ET, last ran on Monday
+	lastRun := time.Date(2024, 3, 11, 9, 20, 0, 0, ny)
+
+	tests := []struct {
+		name    string
+		catchUp time.Duration
+		now     time.Time
+		wantAt  time.Time
+	}{
+		{"missed run inside the window", 15 * time.Minute, time.Date(2024, 3, 12, 9, 30, 0, 0, ny), time.Date(2024, 3, 12, 9, 20, 0, 0, ny)},
+		{"missed run outside the window", 15 * time.Minute, time.Date(2024, 3, 12, 9, 40, 0, 0, ny), time.Time{}},
+		{"only the latest of several misses", time.Hour, time.Date(2024, 3, 14, 9, 50, 0, 0, ny), time.Date(2024, 3, 14, 9, 20, 0, 0, ny)},
+		{"nothing missed", time.Hour, time.Date(2024, 3, 12, 9, 0, 0, 0, ny), time.Time{}},
+		{"catch-up disabled", 0, time.Date(2024, 3, 12, 9, 30, 0, 0, ny), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		runs := 0
+		s, err := newScheduler([]Schedule{{
+			Name:    "premarket",
+			Cron:    "20 9 * * 1-5",
+			CatchUp: Duration(tt.catchUp),
+		}}, filepath.Join(t.TempDir(), "scheduler.json"), nil, func(context.Context, *scheduledJob) error {
+			runs++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s.now = func() time.Time { return tt.now }
+		s.lastRun["premarket"] = lastRun
+
+		s.catchUp(context.Background())
+
+		wantRuns := 0
+		if !tt.wantAt.IsZero() {
+			wantRuns = 1
+		}
+		if runs != wantRuns {
+			t.Errorf("%s: got %d runs, want %d", tt.name, runs, wantRuns)
+		}
+
+		wantLast := lastRun
+		if !tt.wantAt.IsZero() {
+			wantLast = tt.wantAt
+		}
+		if got := s.lastRun["premarket"]; !got.Equal(wantLast) {
+			t.Errorf("%s: last run recorded as %s, want %s", tt.name, got, wantLast)
+		}
+	}
+}
+
+func TestSchedulerCatchUpDuringFallBack(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	est := time.FixedZone("EST", -5*3600)
+
+	runs := 0
+	s, err := newScheduler([]Schedule{{Name: "hourly", Cron: "30 * * * *", CatchUp: Duration(time.Hour)}},
+		filepath.Join(t.TempDir(), "scheduler.json"), nil, func(context.Context, *scheduledJob) error {
+			runs++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last run fell in the repeated hour, this used to never terminate
+	s.lastRun["hourly"] = time.Date(2024, 11, 3, 1, 30, 0, 0, est).In(ny)
+	s.now = func() time.Time { return time.Date(2024, 11, 3, 2, 45, 0, 0, ny) }
+
+	s.catchUp(context.Background())
+
+	if runs != 1 {
+		t.Fatalf("got %d runs, want 1", runs)
+	}
+	if got, want := s.lastRun["hourly"], time.Date(2024, 11, 3, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Fatalf("last run recorded as %s, want %s", got, want)
+	}
+}
+
+func TestSchedulerSkipsClosedDays(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+
+	cal, err := NewCalendar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 0
+	s, err := newScheduler([]Schedule{{Name: "premarket", Cron: "20 9 * * *"}},
+		filepath.Join(t.TempDir(), "scheduler.json"), cal, func(context.Context, *scheduledJob) error {
+			runs++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := s.jobs[0]
+	s.fire(context.Background(), job, time.Date(2024, 12, 25, 9, 20, 0, 0, ny))
+	s.fire(context.Background(), job, time.Date(2024, 12, 28, 9, 20, 0, 0, ny))
+	s.fire(context.Background(), job, time.Date(2024, 12, 26, 9, 20, 0, 0, ny))
+
+	if runs != 1 {
+		t.Fatalf("got %d runs, want only the one on a trading day", runs)
+	}
+
+	job.RunOnClosedDays = true
+	s.fire(context.Background(), job, time.Date(2024, 12, 25, 9, 20, 0, 0, ny))
+	if runs != 2 {
+		t.Fatalf("runOnClosedDays: got %d runs, want 2", runs)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Credentials in the config or the environment can be references to a secret
+// manager instead of the secret itself, resolved once at startup:
+//
+//	vault://secret/stc#rapidapi         field of a Vault KV secret
+//	awssm://prod/stc#rapidapi           key of a JSON secret in AWS Secrets Manager
+//	awssm://prod/rapidapi               a plain text secret
+//	gcpsm://my-project/stc#rapidapi     key of a JSON secret in GCP Secret Manager
+//	gcpsm://my-project/rapidapi/3       a pinned version, latest otherwise
+//
+// The secrets are read with the vault, aws and gcloud binaries, so whatever
+// login they have (tokens, instance roles, workload identity) works as is.
+const (
+	schemeVault = "vault://"
+	schemeAWS   = "awssm://"
+	schemeGCP   = "gcpsm://"
+
+	// How long startup waits on the secret managers
+	secretTimeout = 30 * time.Second
+)
+
+func isSecretRef(v string) bool {
+	return strings.HasPrefix(v, schemeVault) || strings.HasPrefix(v, schemeAWS) || strings.HasPrefix(v, schemeGCP)
+}
+
+// secretResolver reads each secret once, the same secret often holds
+// several keys
+type secretResolver struct {
+	run   func(ctx context.Context, name string, args ...string) ([]byte, error)
+	cache map[string]string
+}
+
+func newSecretResolver() *secretResolver {
+	return &secretResolver{run: runSecretCommand, cache: map[string]string{}}
+}
+
+func runSecretCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return out, nil
+}
+
+// resolve returns the secret a reference points to, anything else is
+// returned unchanged
+func (r *secretResolver) resolve(ctx context.Context, ref string) (string, error) {
+	if !isSecretRef(ref) {
+		return ref, nil
+	}
+
+	scheme, rest, _ := strings.Cut(ref, "://")
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return "", fmt.Errorf("%s: missing the secret name", ref)
+	}
+
+	var args []string
+	switch scheme + "://" {
+	case schemeVault:
+		// vault picks the KV version of the mount itself
+		if field == "" {
+			return "", fmt.Errorf("%s: vault references need a #field", ref)
+		}
+		args = []string{"vault", "kv", "get", "-field=" + field, path}
+		field = ""
+	case schemeAWS:
+		args = []string{"aws", "secretsmanager", "get-secret-value", "--secret-id", path, "--query", "SecretString", "--output", "text"}
+	case schemeGCP:
+		parts := strings.Split(path, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return "", fmt.Errorf("%s: want gcpsm://project/secret[/version]", ref)
+		}
+		version := "latest"
+		if len(parts) == 3 {
+			version = parts[2]
+		}
+		args = []string{"gcloud", "secrets", "versions", "access", version, "--secret=" + parts[1], "--project=" + parts[0]}
+	}
+
+	key := strings.Join(args, " ")
+	value, ok := r.cache[key]
+	if !ok {
+		out, err := r.run(ctx, args[0], args[1:]...)
+		if err != nil {
+			return "", fmt.Errorf("error reading secret %s: %w", ref, err)
+		}
+		value = strings.TrimRight(string(out), "\r\n")
+		r.cache[key] = value
+	}
+
+	if field != "" {
+		var err error
+		if value, err = secretField(value, field); err != nil {
+			return "", fmt.Errorf("secret %s: %w", ref, err)
+		}
+	}
+	if value == "" {
+		return "", fmt.Errorf("secret %s is empty", ref)
+	}
+
+	return value, nil
+}
+
+// secretField picks one key out of a secret stored as a JSON object
+func secretField(secret, field string) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("#%s needs a JSON object secret: %w", field, err)
+	}
+
+	v, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("no string key %q", field)
+	}
+
+	return v, nil
+}
+
+// secrets lists the config fields that hold credentials, by their JSON path.
+// Broker credentials are per account and handled by resolveSecrets.
+func (c *Config) secrets() map[string]*string {
+	s := map[string]*string{
+		"apiKey":                     &c.APIKey,
+		"identifiers.openFigiKey":    &c.Identifiers.OpenFIGIKey,
+		"alerts.notify.slackWebhook": &c.Alerts.Notify.SlackWebhook,
+	}
+
+	if c.Alerts.Notify.Telegram != nil {
+		s["alerts.notify.telegram.botToken"] = &c.Alerts.Notify.Telegram.BotToken
+	}
+
+	return s
+}
+
+// resolveSecrets replaces every secret reference in the credentials with the
+// secret it points to
+func (c *Config) resolveSecrets(ctx context.Context, r *secretResolver) error {
+	for name, field := range c.secrets() {
+		if !isSecretRef(*field) {
+			continue
+		}
+
+		v, err := r.resolve(ctx, *field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = v
+	}
+
+	// Map values can't be pointed to, they're replaced in place
+	for _, a := range c.Accounts {
+		for key, ref := range a.Credentials {
+			v, err := r.resolve(ctx, ref)
+			if err != nil {
+				return fmt.Errorf("accounts.%s.credentials.%s: %w", a.Name, key, err)
+			}
+			a.Credentials[key] = v
+		}
+	}
+
+	return nil
+}
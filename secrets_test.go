@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeSecrets answers secret commands from a table keyed by the command line
+func fakeSecrets(answers map[string]string) (*secretResolver, *[]string) {
+	var calls []string
+	r := newSecretResolver()
+	r.run = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		cmd := strings.Join(append([]string{name}, args...), " ")
+		calls = append(calls, cmd)
+		out, ok := answers[cmd]
+		if !ok {
+			return nil, errors.New("exit status 1")
+		}
+		return []byte(out), nil
+	}
+	return r, &calls
+}
+
+func TestResolveSecrets(t *testing.T) {
+	r, calls := fakeSecrets(map[string]string{
+		"vault kv get -field=rapidapi secret/stc":                                                     "rapid-key\n",
+		"aws secretsmanager get-secret-value --secret-id prod/stc --query SecretString --output text": `{"slack": "https://hooks.slack.com/x", "telegram": "123:abc"}` + "\n",
+		"gcloud secrets versions access 3 --secret=ibkr --project=trading":                            "ibkr-password",
+	})
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "vault://secret/stc#rapidapi"
+	cfg.Identifiers.OpenFIGIKey = "plain-key"
+	cfg.Alerts.Notify.SlackWebhook = "awssm://prod/stc#slack"
+	cfg.Alerts.Notify.Telegram = &TelegramConfig{BotToken: "awssm://prod/stc#telegram", ChatID: "42"}
+	cfg.Accounts = []Account{{Name: "main", AccountBalance: 1000, Credentials: map[string]string{
+		"user":     "trader",
+		"password": "gcpsm://trading/ibkr/3",
+	}}}
+
+	if err := cfg.resolveSecrets(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.APIKey != "rapid-key" || cfg.Identifiers.OpenFIGIKey != "plain-key" {
+		t.Errorf("got keys %q and %q", cfg.APIKey, cfg.Identifiers.OpenFIGIKey)
+	}
+	if cfg.Alerts.Notify.SlackWebhook != "https://hooks.slack.com/x" || cfg.Alerts.Notify.Telegram.BotToken != "123:abc" {
+		t.Errorf("got notify %+v, %+v", cfg.Alerts.Notify, cfg.Alerts.Notify.Telegram)
+	}
+	if creds := cfg.Accounts[0].Credentials; creds["user"] != "trader" || creds["password"] != "ibkr-password" {
+		t.Errorf("got credentials %v", creds)
+	}
+
+	// Both keys of prod/stc come from one read
+	if len(*calls) != 3 {
+		t.Errorf("got %d secret reads, want 3: %v", len(*calls), *calls)
+	}
+}
+
+func TestResolveSecretErrors(t *testing.T) {
+	r, _ := fakeSecrets(map[string]string{
+		"aws secretsmanager get-secret-value --secret-id plain --query SecretString --output text": "not json",
+	})
+
+	for _, ref := range []string{
+		"vault://secret/stc",
+		"vault://secret/missing#key",
+		"awssm://plain#key",
+		"gcpsm://only-project",
+		"awssm://#key",
+	} {
+		v, err := r.resolve(context.Background(), ref)
+		if err == nil {
+			t.Errorf("%s: got %q, want error", ref, v)
+		}
+	}
+
+	// The config field is named, the secret value never is
+	cfg := DefaultConfig()
+	cfg.APIKey = "vault://secret/missing#key"
+	if err := cfg.resolveSecrets(context.Background(), r); err == nil || !strings.HasPrefix(err.Error(), "apiKey: ") {
+		t.Errorf("got %v, want an error naming apiKey", err)
+	}
+}
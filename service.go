@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Everything the service definitions are rendered from
+type serviceSpec struct {
+	Name       string
+	Executable string
+	Args       []string
+	WorkDir    string
+	User       string
+	EnvFile    string
+	Env        map[string]string
+}
+
+// Quoted command line for systemd's ExecStart
+func (s serviceSpec) CommandLine() string {
+	parts := []string{systemdQuote(s.Executable)}
+	for _, a := range s.Args {
+		parts = append(parts, systemdQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s serviceSpec) SortedEnv() []string {
+	var env []string
+	for k, v := range s.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\$%") {
+		return s
+	}
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`).Replace(s)
+	return `"` + s + `"`
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+var systemdUnit = template.Must(template.New("systemd").Funcs(template.FuncMap{"q": systemdQuote}).Parse(`[Unit]
+Description=Stock trading CLI daemon ({{.Name}})
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+User={{.User}}
+WorkingDirectory={{.WorkDir}}
+{{- if .EnvFile}}
+EnvironmentFile={{.EnvFile}}
+{{- end}}
+{{- range .SortedEnv}}
+Environment={{q .}}
+{{- end}}
+ExecStart={{.CommandLine}}
+Restart=always
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+var launchdPlist = template.Must(template.New("launchd").Funcs(template.FuncMap{"x": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{x .Name}}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{x .Executable}}</string>
+		{{- range .Args}}
+		<string>{{x .}}</string>
+		{{- end}}
+	</array>
+	<key>WorkingDirectory</key>
+	<string>{{x .WorkDir}}</string>
+	<key>UserName</key>
+	<string>{{x .User}}</string>
+	{{- if .Env}}
+	<key>EnvironmentVariables</key>
+	<dict>
+		{{- range $k, $v := .Env}}
+		<key>{{x $k}}</key>
+		<string>{{x $v}}</string>
+		{{- end}}
+	</dict>
+	{{- end}}
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>{{x .WorkDir}}/{{x .Name}}.log</string>
+	<key>StandardErrorPath</key>
+	<string>{{x .WorkDir}}/{{x .Name}}.log</string>
+</dict>
+</plist>
+`))
+
+// Windows services have to speak the service control protocol, which needs
+// more than the standard library. A task that starts at boot and restarts on
+// failure gives the same result with the built-in Task Scheduler.
+var windowsTask = template.Must(template.New("windows").Funcs(template.FuncMap{"x": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <RegistrationInfo>
+    <Description>Stock trading CLI daemon ({{x .Name}})</Description>
+  </RegistrationInfo>
+  <Triggers>
+    <BootTrigger>
+      <Enabled>true</Enabled>
+    </BootTrigger>
+  </Triggers>
+  <Principals>
+    <Principal id="Author">
+      <UserId>{{x .User}}</UserId>
+      <LogonType>S4U</LogonType>
+    </Principal>
+  </Principals>
+  <Settings>
+    <MultipleInstancesPolicy>IgnoreNew</MultipleInstancesPolicy>
+    <ExecutionTimeLimit>PT0S</ExecutionTimeLimit>
+    <RestartOnFailure>
+      <Interval>PT1M</Interval>
+      <Count>999</Count>
+    </RestartOnFailure>
+  </Settings>
+  <Actions Context="Author">
+    <Exec>
+      <Command>{{x .Executable}}</Command>
+      <Arguments>{{range $i, $a := .Args}}{{if $i}} {{end}}"{{x $a}}"{{end}}</Arguments>
+      <WorkingDirectory>{{x .WorkDir}}</WorkingDirectory>
+    </Exec>
+  </Actions>
+</Task>
+`))
+
+// renderService returns the service definition for the platform and where it belongs
+func renderService(platform string, spec serviceSpec) (string, []byte, error) {
+	var tmpl *template.Template
+	var path string
+
+	switch platform {
+	case "linux":
+		tmpl, path = systemdUnit, filepath.Join("/etc/systemd/system", spec.Name+".service")
+	case "darwin":
+		tmpl, path = launchdPlist, filepath.Join("/Library/LaunchDaemons", spec.Name+".plist")
+	case "windows":
+		if len(spec.Env) > 0 || spec.EnvFile != "" {
+			return "", nil, fmt.Errorf("environment variables can't be set for a scheduled task, set them for user %s instead", spec.User)
+		}
+		tmpl, path = windowsTask, filepath.Join(spec.WorkDir, spec.Name+".xml")
+	default:
+		return "", nil, fmt.Errorf("no service definition for %s", platform)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, spec); err != nil {
+		return "", nil, err
+	}
+
+	return path, buf.Bytes(), nil
+}
+
+// The commands that register and start the installed definition
+func activateCommands(platform, name, path string) [][]string {
+	switch platform {
+	case "linux":
+		return [][]string{
+			{"systemctl", "daemon-reload"},
+			{"systemctl", "enable", "--now", name + ".service"},
+		}
+	case "darwin":
+		return [][]string{
+			{"launchctl", "load", "-w", path},
+		}
+	case "windows":
+		return [][]string{
+			{"schtasks", "/Create", "/TN", name, "/XML", path, "/F"},
+			{"schtasks", "/Run", "/TN", name},
+		}
+	}
+	return nil
+}
+
+// Repeatable KEY=VALUE flag
+type envFlag map[string]string
+
+func (e envFlag) String() string {
+	return strings.Join(serviceSpec{Env: e}.SortedEnv(), ",")
+}
+
+func (e envFlag) Set(v string) error {
+	k, val, ok := strings.Cut(v, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("want KEY=VALUE, got %q", v)
+	}
+	e[k] = val
+	return nil
+}
+
+// Generates and installs a system service that runs the daemon
+func installServiceCommand(ctx context.Context, args []string) error {
+	env := envFlag{}
+
+	fs := flag.NewFlagSet("install-service", flag.ExitOnError)
+	name := fs.String("name", "stocktradingcli", "service name")
+	configPath := fs.String("config", "", "config file the daemon runs with (required)")
+	runAs := fs.String("user", "", "user the daemon runs as, the current user by default")
+	workDir := fs.String("workdir", "", "working directory, the current directory by default")
+	listen := fs.String("listen", "", "health check address passed to the daemon, e.g. :8080")
+	envFile := fs.String("env-file", "", "file with KEY=VALUE lines (API keys) loaded by systemd")
+	platform := fs.String("platform", runtime.GOOS, "linux (systemd), darwin (launchd) or windows (Task Scheduler)")
+	dryRun := fs.Bool("dry-run", false, "print the service definition instead of installing it")
+	fs.Var(env, "env", "environment variable KEY=VALUE for the daemon, repeatable")
+	fs.Parse(args)
+
+	if *configPath == "" {
+		return fmt.Errorf("-config is required, the daemon takes its schedules from it")
+	}
+
+	// Validate now rather than when the service keeps restarting
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Schedules) == 0 {
+		return fmt.Errorf("%s has no schedules, the daemon would have nothing to do", *configPath)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	if *workDir == "" {
+		if *workDir, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+
+	if *runAs == "" {
+		u, err := user.Current()
+		if err != nil {
+			return err
+		}
+		*runAs = u.Username
+	}
+
+	absConfig, err := filepath.Abs(*configPath)
+	if err != nil {
+		return err
+	}
+
+	spec := serviceSpec{
+		Name:       *name,
+		Executable: exe,
+		Args:       []string{"daemon", "-config", absConfig},
+		WorkDir:    *workDir,
+		User:       *runAs,
+		EnvFile:    *envFile,
+		Env:        env,
+	}
+	if *listen != "" {
+		spec.Args = append(spec.Args, "-listen", *listen)
+	}
+
+	if *envFile != "" && *platform != "linux" {
+		return fmt.Errorf("-env-file is only supported with systemd, use -env on %s", *platform)
+	}
+
+	path, data, err := renderService(*platform, spec)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("# %s\n%s", path, data)
+		for _, cmd := range activateCommands(*platform, *name, path) {
+			fmt.Printf("# then: %s\n", strings.Join(cmd, " "))
+		}
+		return nil
+	}
+
+	// The definition may hold API keys passed with -env
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing %s (try sudo or -dry-run): %w", path, err)
+	}
+	log.Printf("Wrote %s", path)
+
+	for _, args := range activateCommands(*platform, *name, path) {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+		}
+	}
+
+	log.Printf("Installed and started service %s", *name)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderService(t *testing.T) {
+	spec := serviceSpec{
+		Name:       "stocktradingcli",
+		Executable: "/opt/stock trading/stocktradingcli",
+		Args:       []string{"daemon", "-config", "/etc/stocktradingcli/config.json"},
+		WorkDir:    "/var/lib/stocktradingcli",
+		User:       "trader",
+		EnvFile:    "/etc/stocktradingcli/env",
+		Env:        map[string]string{"TZ": "UTC", "NOTE": "50% size & $5"},
+	}
+
+	path, data, err := renderService("linux", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/etc/systemd/system/stocktradingcli.service" {
+		t.Errorf("systemd path: got %s", path)
+	}
+
+	unit := string(data)
+	for _, want := range []string{
+		"User=trader\n",
+		"EnvironmentFile=/etc/stocktradingcli/env\n",
+		"Environment=\"NOTE=50%% size & $$5\"\nEnvironment=TZ=UTC\n",
+		"ExecStart=\"/opt/stock trading/stocktradingcli\" daemon -config /etc/stocktradingcli/config.json\n",
+		"Restart=always\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("systemd unit is missing %q:\n%s", want, unit)
+		}
+	}
+
+	_, data, err = renderService("darwin", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plist := string(data); !strings.Contains(plist, "<string>50% size &amp; $5</string>") || !strings.Contains(plist, "<string>daemon</string>") {
+		t.Errorf("launchd plist not escaped as expected:\n%s", plist)
+	}
+
+	if _, _, err := renderService("windows", spec); err == nil {
+		t.Error("windows: expected an error for environment variables")
+	}
+
+	spec.Env, spec.EnvFile = nil, ""
+	_, data, err = renderService("windows", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task := string(data); !strings.Contains(task, `<Arguments>"daemon" "-config" "/etc/stocktradingcli/config.json"</Arguments>`) {
+		t.Errorf("windows task arguments:\n%s", task)
+	}
+
+	if _, _, err := renderService("plan9", spec); err == nil {
+		t.Error("expected an error for an unsupported platform")
+	}
+}
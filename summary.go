@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A ticker whose selection could not be completed
+type Failure struct {
+	Ticker string `json:"ticker"`
+	Error  string `json:"error"`
+}
+
+// Summary is the record of one run, it is kept in the runs directory
+type Summary struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Input      string    `json:"input"`
+	Output     string    `json:"output,omitempty"`
+	Selected   int       `json:"selected"`
+	Failures   []Failure `json:"failures"`
+
+	// Account the plan was sized for, when several are configured
+	Account string `json:"account,omitempty"`
+
+	// Freeform labels given on the command line, to find the run again later
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
+
+	// Decisions of the -review step, in the order they were made
+	Reviews []ReviewDecision `json:"reviews,omitempty"`
+}
+
+func newSummary(inputPath string) *Summary {
+	now := time.Now()
+
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+
+	return &Summary{
+		ID:        now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		StartedAt: now,
+		Input:     inputPath,
+		Failures:  []Failure{},
+	}
+}
+
+func (s *Summary) add(sel Selection) {
+	s.Selected++
+	if sel.Status == StatusError {
+		s.Failures = append(s.Failures, Failure{Ticker: sel.Ticker, Error: sel.Error})
+	}
+}
+
+// Save writes the summary to <dir>/<id>.json
+func (s *Summary) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating runs directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding summary: %w", err)
+	}
+
+	path := filepath.Join(dir, s.ID+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("error writing summary: %w", err)
+	}
+
+	return path, nil
+}
+
+func (s *Summary) Log() {
+	if s.Account != "" {
+		log.Printf("Account %s", s.Account)
+	}
+	log.Printf("Run %s: %d selections, %d failures in %s", s.ID, s.Selected, len(s.Failures),
+		s.FinishedAt.Sub(s.StartedAt).Round(time.Millisecond))
+
+	if len(s.Tags) > 0 || s.Note != "" {
+		log.Printf("  tags %s, note %q", strings.Join(s.Tags, ","), s.Note)
+	}
+
+	for _, f := range s.Failures {
+		log.Printf("  failed %s: %s", f.Ticker, f.Error)
+	}
+}
+
+// OutputMeta says which run a plan came from. The plan itself is a bare
+// array of selections, so it's written next to it: opg.json -> opg.meta.json
+type OutputMeta struct {
+	Run        string    `json:"run"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Input      string    `json:"input"`
+	Account    string    `json:"account,omitempty"`
+	Selected   int       `json:"selected"`
+	Tags       []string  `json:"tags,omitempty"`
+	Note       string    `json:"note,omitempty"`
+}
+
+func metaPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".meta.json"
+}
+
+// writeOutputMeta returns the name the file got, which has the encryption's
+// extension when it is enabled, like the plan
+func writeOutputMeta(path string, s *Summary, enc Encryption) (string, error) {
+	data, err := json.MarshalIndent(OutputMeta{
+		Run:        s.ID,
+		FinishedAt: s.FinishedAt,
+		Input:      s.Input,
+		Account:    s.Account,
+		Selected:   s.Selected,
+		Tags:       s.Tags,
+		Note:       s.Note,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding output metadata: %w", err)
+	}
+
+	f, path, err := createOutput(path, enc)
+	if err != nil {
+		return "", fmt.Errorf("error creating output metadata: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return "", fmt.Errorf("error writing output metadata: %w", err)
+	}
+
+	return path, f.Close()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provider names used in Venue.Providers
+const providerSeekingAlpha = "seekingAlpha"
+
+// Venue is an exchange tickers are listed on outside the US main markets,
+// told apart by the ticker's suffix (RY.TO, VOD.L)
+type Venue struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+
+	// Regular session in the venue's timezone, "HH:MM"
+	Timezone string `json:"timezone"`
+	Opens    string `json:"opens"`
+	Closes   string `json:"closes"`
+
+	// What replaces the suffix in the symbol each provider expects. A provider
+	// that isn't listed doesn't cover the venue.
+	Providers map[string]string `json:"providers,omitempty"`
+}
+
+// Built-in venues by ticker suffix, the config can add more or replace them
+var venues = map[string]Venue{
+	".TO": {Name: "Toronto Stock Exchange", Currency: "CAD", Timezone: "America/Toronto", Opens: "09:30", Closes: "16:00",
+		Providers: map[string]string{providerSeekingAlpha: ":CA"}},
+	".V": {Name: "TSX Venture Exchange", Currency: "CAD", Timezone: "America/Toronto", Opens: "09:30", Closes: "16:00",
+		Providers: map[string]string{providerSeekingAlpha: ":CA"}},
+	".L":  {Name: "London Stock Exchange", Currency: "GBX", Timezone: "Europe/London", Opens: "08:00", Closes: "16:30"},
+	".DE": {Name: "Xetra", Currency: "EUR", Timezone: "Europe/Berlin", Opens: "09:00", Closes: "17:30"},
+	".PA": {Name: "Euronext Paris", Currency: "EUR", Timezone: "Europe/Paris", Opens: "09:00", Closes: "17:30"},
+	".AS": {Name: "Euronext Amsterdam", Currency: "EUR", Timezone: "Europe/Amsterdam", Opens: "09:00", Closes: "17:30"},
+	".SW": {Name: "SIX Swiss Exchange", Currency: "CHF", Timezone: "Europe/Zurich", Opens: "09:00", Closes: "17:30"},
+	".HK": {Name: "Hong Kong Stock Exchange", Currency: "HKD", Timezone: "Asia/Hong_Kong", Opens: "09:30", Closes: "16:00"},
+	".T":  {Name: "Tokyo Stock Exchange", Currency: "JPY", Timezone: "Asia/Tokyo", Opens: "09:00", Closes: "15:30"},
+	".AX": {Name: "Australian Securities Exchange", Currency: "AUD", Timezone: "Australia/Sydney", Opens: "10:00", Closes: "16:00"},
+}
+
+// OTC foreign ordinaries (NSRGF) and ADRs (NSRGY) trade in New York hours and
+// dollars, and providers know them by their plain symbol
+var otcVenue = Venue{Name: "OTC Markets", Currency: "USD", Timezone: defaultTimezone, Opens: "09:30", Closes: "16:00"}
+
+func (v Venue) Validate(suffix string) error {
+	if !strings.HasPrefix(suffix, ".") {
+		return fmt.Errorf("venues.%s: the suffix must start with a dot", suffix)
+	}
+	if v.Currency == "" {
+		return fmt.Errorf("venues.%s.currency is required", suffix)
+	}
+	if _, err := time.LoadLocation(v.Timezone); err != nil {
+		return fmt.Errorf("venues.%s.timezone: %w", suffix, err)
+	}
+	if _, err := time.Parse("15:04", v.Opens); err != nil {
+		return fmt.Errorf("venues.%s.opens %q: want HH:MM", suffix, v.Opens)
+	}
+	if _, err := time.Parse("15:04", v.Closes); err != nil {
+		return fmt.Errorf("venues.%s.closes %q: want HH:MM", suffix, v.Closes)
+	}
+	return nil
+}
+
+// Session returns when the venue opens and closes on t's day in its
+// timezone. Only weekends are known to be closed, not local holidays.
+func (v Venue) Session(t time.Time) (opens, closes time.Time, open bool) {
+	loc, err := time.LoadLocation(v.Timezone)
+	if err != nil {
+		return opens, closes, false
+	}
+
+	t = t.In(loc)
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return opens, closes, false
+	}
+
+	opens, err = clockToday(v.Opens, t)
+	if err != nil {
+		return opens, closes, false
+	}
+	closes, err = clockToday(v.Closes, t)
+	if err != nil {
+		return opens, closes, false
+	}
+
+	return opens, closes, true
+}
+
+// venue finds the venue of a ticker by its suffix, or OTC for five letter
+// symbols ending in F or Y. US listings, including share classes like BRK.A,
+// have no venue.
+func (c Config) venue(ticker string) (string, Venue, bool) {
+	best := ""
+	for _, table := range []map[string]Venue{venues, c.Venues} {
+		for suffix := range table {
+			if len(suffix) > len(best) && len(ticker) > len(suffix) && strings.HasSuffix(ticker, suffix) {
+				best = suffix
+			}
+		}
+	}
+
+	if best != "" {
+		if v, ok := c.Venues[best]; ok {
+			return best, v, true
+		}
+		return best, venues[best], true
+	}
+
+	if isOTC(ticker) {
+		return "", otcVenue, true
+	}
+
+	return "", Venue{}, false
+}
+
+func isOTC(ticker string) bool {
+	if len(ticker) != 5 || !strings.ContainsAny(ticker[4:], "FY") {
+		return false
+	}
+	for i := 0; i < len(ticker); i++ {
+		if !isUpper(ticker[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// providerSymbol returns the symbol a provider knows the ticker by, or an
+// error when the provider doesn't cover the ticker's venue
+func (c Config) providerSymbol(provider, ticker string) (string, error) {
+	suffix, v, ok := c.venue(ticker)
+	if !ok || suffix == "" {
+		return ticker, nil
+	}
+
+	replacement, ok := v.Providers[provider]
+	if !ok {
+		return "", fmt.Errorf("%s doesn't cover %s (%s)", provider, v.Name, ticker)
+	}
+
+	return strings.TrimSuffix(ticker, suffix) + replacement, nil
+}
+
+// currencyRate is what one unit of currency is worth in the account currency.
+// London quotes in pence, GBX is worth a hundredth of the GBP rate.
+func (c Config) currencyRate(currency string) (float64, error) {
+	switch {
+	case currency == c.accountCurrency():
+		return 1, nil
+	case currency == "GBX":
+		rate, err := c.currencyRate("GBP")
+		return rate / 100, err
+	case c.Rates[currency] > 0:
+		return c.Rates[currency], nil
+	}
+
+	return 0, fmt.Errorf("no rate to convert %s to %s, add it to rates", currency, c.accountCurrency())
+}
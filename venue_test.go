@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVenue(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Venues = map[string]Venue{".MI": {Name: "Borsa Italiana", Currency: "EUR", Timezone: "Europe/Rome", Opens: "09:00", Closes: "17:30"}}
+
+	for ticker, want := range map[string]string{
+		"RY.TO":  "Toronto Stock Exchange",
+		"VOD.L":  "London Stock Exchange",
+		"ENI.MI": "Borsa Italiana",
+		"NSRGY":  "OTC Markets",
+		"NSRGF":  "OTC Markets",
+	} {
+		if _, v, ok := cfg.venue(ticker); !ok || v.Name != want {
+			t.Errorf("%s: got %q %v, want %q", ticker, v.Name, ok, want)
+		}
+	}
+
+	// US listings, share classes included
+	for _, ticker := range []string{"AAPL", "BRK.A", "BF.B", "GOOGL", "META", ".L"} {
+		if _, v, ok := cfg.venue(ticker); ok {
+			t.Errorf("%s: got venue %q, want none", ticker, v.Name)
+		}
+	}
+}
+
+func TestProviderSymbol(t *testing.T) {
+	cfg := DefaultConfig()
+
+	for ticker, want := range map[string]string{"RY.TO": "RY:CA", "SHOP.V": "SHOP:CA", "NSRGY": "NSRGY", "BRK.A": "BRK.A"} {
+		got, err := cfg.providerSymbol(providerSeekingAlpha, ticker)
+		if err != nil || got != want {
+			t.Errorf("%s: got %q %v, want %q", ticker, got, err, want)
+		}
+	}
+
+	if _, err := cfg.providerSymbol(providerSeekingAlpha, "VOD.L"); err == nil {
+		t.Error("VOD.L: want an error, London isn't covered")
+	}
+}
+
+func TestCalculateForeignListing(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rates = map[string]float64{"GBP": 1.25}
+	e := NewEngine(cfg, nil)
+
+	// 200 risk, the stop is 8.89 pence or 0.111125 dollars away
+	p := e.Calculate("VOD.L", -.1, 100)
+	if p.Shares != 1799 || p.Profit != 199.91 {
+		t.Errorf("got %d shares, profit %v, want 1799 and 199.91", p.Shares, p.Profit)
+	}
+	if got := e.priceCurrency("VOD.L"); got != "GBX" {
+		t.Errorf("got currency %s, want GBX", got)
+	}
+
+	// No CAD rate, nothing is sized rather than sized in the wrong currency
+	if p := e.Calculate("RY.TO", -.1, 100); p.Shares != 0 {
+		t.Errorf("RY.TO: got %d shares without a CAD rate", p.Shares)
+	}
+}
+
+func TestVenueSession(t *testing.T) {
+	// 14:00 UTC is 15:00 in London during winter
+	opens, closes, open := venues[".L"].Session(time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC))
+	if !open || opens.UTC().Hour() != 8 || closes.UTC().Format("15:04") != "16:30" {
+		t.Errorf("got %v to %v open %v", opens, closes, open)
+	}
+
+	if _, _, open := venues[".T"].Session(time.Date(2024, 1, 13, 3, 0, 0, 0, time.UTC)); open {
+		t.Error("Tokyo open on a Saturday")
+	}
+}
+
+func TestVenueValidate(t *testing.T) {
+	for suffix, v := range map[string]Venue{
+		"MI":  {Currency: "EUR", Timezone: "Europe/Rome", Opens: "09:00", Closes: "17:30"},
+		".MI": {Timezone: "Europe/Rome", Opens: "09:00", Closes: "17:30"},
+		".XX": {Currency: "EUR", Timezone: "Nowhere/Town", Opens: "09:00", Closes: "17:30"},
+		".YY": {Currency: "EUR", Timezone: "Europe/Rome", Opens: "9am", Closes: "17:30"},
+	} {
+		if err := v.Validate(suffix); err == nil {
+			t.Errorf("%s: want error", suffix)
+		}
+	}
+}
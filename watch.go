@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Re-runs the plan every interval during the premarket, so the output follows
+// the gap data as it updates closer to the open
+func watchCommand(ctx context.Context, args []string) error {
+	var opts options
+
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	opts.register(fs)
+	interval := fs.Duration("interval", 5*time.Minute, "time between runs")
+	until := fs.String("until", "", "stop watching at this time of day (HH:MM) in -tz, e.g. 09:30")
+	tz := fs.String("tz", defaultTimezone, "timezone for -until, exchange time by default")
+	fs.Parse(args)
+
+	if *interval <= 0 {
+		return errors.New("-interval must be positive")
+	}
+
+	if *until != "" {
+		loc, err := time.LoadLocation(*tz)
+		if err != nil {
+			return err
+		}
+
+		stopAt, err := untilToday(*until, time.Now().In(loc))
+		if err != nil {
+			return err
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, stopAt)
+		defer cancel()
+
+		log.Printf("Watching %s every %s until %s", opts.inputPath, *interval, stopAt.Format("15:04 MST"))
+	} else {
+		log.Printf("Watching %s every %s", opts.inputPath, *interval)
+	}
+
+	engine, done, err := opts.setup()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return every(ctx, *interval, func(ctx context.Context) {
+		// Passes outside the window are skipped, the window may open later
+		if !opts.ignoreWindow {
+			if err := engine.Config().checkWindow(time.Now()); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+
+		opts.rewind()
+
+		// A failed pass is logged, the next one may well succeed once the data updates
+		if _, err := runOnce(ctx, engine, &opts, opts.inputPath, opts.outputPath); err != nil && ctx.Err() == nil {
+			log.Printf("Error running scan, %v", err)
+		}
+	})
+}
+
+// every calls fn straight away and then once per interval until ctx is done.
+// Ticks that fall inside a slow run are skipped rather than queued.
+func every(ctx context.Context, interval time.Duration, fn func(context.Context)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		// A tick that came in during a slow run would otherwise win half
+		// the time against the deadline and run fn past it
+		select {
+		case <-ticker.C:
+			if ctx.Err() == nil {
+				continue
+			}
+		case <-ctx.Done():
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil
+		}
+		return ctx.Err()
+	}
+}
+
+// untilToday is when -until stops watching, which has to be later today
+func untilToday(until string, now time.Time) (time.Time, error) {
+	stopAt, err := clockToday(until, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !stopAt.After(now) {
+		return time.Time{}, fmt.Errorf("-until %s %s has already passed", until, now.Location())
+	}
+
+	return stopAt, nil
+}
+
+// clockToday returns hh:mm on now's day, in now's location
+func clockToday(hhmm string, now time.Time) (time.Time, error) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day %q, want HH:MM", hhmm)
+	}
+
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClockToday(t *testing.T) {
+	ny, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 7, 10, 8, 15, 0, 0, ny)
+
+	tests := []struct {
+		hhmm    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"09:30", time.Date(2024, 7, 10, 9, 30, 0, 0, ny), false},
+		{"00:00", time.Date(2024, 7, 10, 0, 0, 0, 0, ny), false},
+		{"23:59", time.Date(2024, 7, 10, 23, 59, 0, 0, ny), false},
+		{"9:30", time.Date(2024, 7, 10, 9, 30, 0, 0, ny), false},
+		{"24:00", time.Time{}, true},
+		{"9am", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := clockToday(tt.hhmm, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %v", tt.hhmm, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) || (err == nil && got.Location() != ny) {
+			t.Errorf("%q: got %s, want %s", tt.hhmm, got, tt.want)
+		}
+	}
+}
+
+func TestUntilToday(t *testing.T) {
+	ny, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 7, 10, 8, 15, 0, 0, ny)
+
+	tests := []struct {
+		until   string
+		wantErr bool
+	}{
+		{"09:30", false},
+		{"08:16", false},
+		// Now is already too late
+		{"08:15", true},
+		{"07:00", true},
+		{"nine", true},
+	}
+
+	for _, tt := range tests {
+		stopAt, err := untilToday(tt.until, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got %s, %v, want error %v", tt.until, stopAt, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEvery(t *testing.T) {
+	tests := []struct {
+		name    string
+		stop    func(cancel context.CancelFunc, calls int)
+		timeout time.Duration
+		want    error
+	}{
+		// Deadlines are how -until ends a watch, that's a normal end
+		{"deadline", func(context.CancelFunc, int) {}, 35 * time.Millisecond, nil},
+		{"cancelled", func(cancel context.CancelFunc, calls int) {
+			if calls == 3 {
+				cancel()
+			}
+		}, time.Second, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+
+		calls := 0
+		err := every(ctx, 10*time.Millisecond, func(context.Context) {
+			calls++
+			tt.stop(cancel, calls)
+		})
+		cancel()
+
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		// Straight away, then once per tick
+		if calls < 2 {
+			t.Errorf("%s: fn called %d times", tt.name, calls)
+		}
+	}
+
+	// A run slower than the interval skips the ticks it overlapped and
+	// doesn't go past the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	every(ctx, 5*time.Millisecond, func(context.Context) {
+		calls++
+		time.Sleep(20 * time.Millisecond)
+	})
+	if calls > 4 {
+		t.Errorf("slow fn called %d times, ticks were queued", calls)
+	}
+}
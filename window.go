@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	enforceRefuse = "refuse"
+	enforceWarn   = "warn"
+)
+
+// RunWindow is the time of day runs are allowed in, in exchange time unless
+// another timezone is given. DST is handled by the timezone database, so
+// "08:00" stays 08:00 New York time all year round.
+type RunWindow struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+
+	// "refuse" (default) stops the run, "warn" only logs
+	Enforce string `json:"enforce,omitempty"`
+}
+
+func (w RunWindow) Validate() error {
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("runWindow.start %q: want HH:MM", w.Start)
+	}
+	if _, err := time.Parse("15:04", w.End); err != nil {
+		return fmt.Errorf("runWindow.end %q: want HH:MM", w.End)
+	}
+	if _, err := w.location(); err != nil {
+		return fmt.Errorf("runWindow.timezone: %w", err)
+	}
+	if w.Enforce != "" && w.Enforce != enforceRefuse && w.Enforce != enforceWarn {
+		return fmt.Errorf("runWindow.enforce must be %q or %q, got %q", enforceRefuse, enforceWarn, w.Enforce)
+	}
+
+	return nil
+}
+
+func (w RunWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.LoadLocation(defaultTimezone)
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// Contains reports whether now falls inside the window. A window whose end
+// is before its start runs over midnight.
+func (w RunWindow) Contains(now time.Time) (bool, error) {
+	loc, err := w.location()
+	if err != nil {
+		return false, err
+	}
+
+	now = now.In(loc)
+	start, err := clockToday(w.Start, now)
+	if err != nil {
+		return false, err
+	}
+	end, err := clockToday(w.End, now)
+	if err != nil {
+		return false, err
+	}
+
+	if !end.After(start) {
+		return !now.Before(start) || now.Before(end), nil
+	}
+
+	return !now.Before(start) && now.Before(end), nil
+}
+
+// checkWindow returns an error when the run window is enforced and now is
+// outside of it. Without a configured window everything is allowed.
+func (c Config) checkWindow(now time.Time) error {
+	w := c.RunWindow
+	if w == nil {
+		return nil
+	}
+
+	ok, err := w.Contains(now)
+	if err != nil || ok {
+		return err
+	}
+
+	loc, _ := w.location()
+	msg := fmt.Sprintf("it is %s, outside the run window %s-%s %s", now.In(loc).Format("15:04 MST"), w.Start, w.End, loc)
+
+	if w.Enforce == enforceWarn {
+		log.Printf("Warning: %s", msg)
+		return nil
+	}
+
+	return fmt.Errorf("refusing to run: %s (use -ignore-window to run anyway)", msg)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunWindowContains(t *testing.T) {
+	premarket := RunWindow{Start: "08:00", End: "09:28"}
+	overnight := RunWindow{Start: "22:00", End: "02:00", Timezone: "UTC"}
+
+	tests := []struct {
+		name   string
+		window RunWindow
+		now    time.Time
+		want   bool
+	}{
+		// The server runs in UTC, the window follows New York across DST
+		{"winter inside", premarket, time.Date(2024, 1, 10, 13, 30, 0, 0, time.UTC), true},
+		{"winter before", premarket, time.Date(2024, 1, 10, 12, 30, 0, 0, time.UTC), false},
+		{"summer inside", premarket, time.Date(2024, 7, 10, 12, 30, 0, 0, time.UTC), true},
+		{"summer after", premarket, time.Date(2024, 7, 10, 13, 30, 0, 0, time.UTC), false},
+		{"end is exclusive", premarket, time.Date(2024, 7, 10, 13, 28, 0, 0, time.UTC), false},
+		{"start is inclusive", premarket, time.Date(2024, 7, 10, 12, 0, 0, 0, time.UTC), true},
+		{"overnight late", overnight, time.Date(2024, 7, 10, 23, 0, 0, 0, time.UTC), true},
+		{"overnight early", overnight, time.Date(2024, 7, 10, 1, 0, 0, 0, time.UTC), true},
+		{"overnight outside", overnight, time.Date(2024, 7, 10, 12, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.window.Contains(tt.now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Contains(%s) = %v, want %v", tt.name, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestCheckWindow(t *testing.T) {
+	outside := time.Date(2024, 7, 10, 18, 0, 0, 0, time.UTC)
+
+	cfg := DefaultConfig()
+	if err := cfg.checkWindow(outside); err != nil {
+		t.Errorf("no window: %v", err)
+	}
+
+	cfg.RunWindow = &RunWindow{Start: "08:00", End: "09:28"}
+	if err := cfg.checkWindow(outside); err == nil {
+		t.Error("refuse: expected an error outside the window")
+	}
+
+	cfg.RunWindow.Enforce = enforceWarn
+	if err := cfg.checkWindow(outside); err != nil {
+		t.Errorf("warn: %v", err)
+	}
+
+	cfg.RunWindow = &RunWindow{Start: "8am", End: "09:28"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an invalid start time to fail validation")
+	}
+}